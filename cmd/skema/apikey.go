@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/iamajraj/skema/internal/server"
+)
+
+// runAPIKey implements `skema apikey --name ci`: it generates a random key
+// and prints the hash to put in the auth section.
+func runAPIKey(args []string) {
+	fs := flag.NewFlagSet("apikey", flag.ExitOnError)
+	name := fs.String("name", "my-key", "Name of the key")
+	hashOnly := fs.String("hash", "", "Print the hash of an existing key instead of generating one")
+	fs.Parse(args)
+
+	if *hashOnly != "" {
+		fmt.Println(server.HashAPIKey(*hashOnly))
+		return
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Generating key failed: %v", err)
+	}
+	key := "sk_" + hex.EncodeToString(b)
+
+	fmt.Printf("🔑 API key (shown once, give it to the client): %s\n\n", key)
+	fmt.Println("Add to skema.yml:")
+	fmt.Printf(`auth:
+  api_keys:
+    - name: %s
+      hash: %s
+      scopes: ["*:read"]
+`, *name, server.HashAPIKey(key))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iamajraj/skema/internal/server"
+)
+
+// runImport implements `skema import --entity Product --file products.csv`.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := fs.String("config", "skema.yml", "Path to the configuration file")
+	entity := fs.String("entity", "", "Entity to import into (e.g. Product)")
+	file := fs.String("file", "", "CSV or JSON file to import")
+	format := fs.String("format", "", "Input format: csv or json (default: from the file extension)")
+	columns := fs.String("map", "", "Map input columns to fields, e.g. \"Product Name=name,SKU=-\"")
+	dryRun := fs.Bool("dry-run", false, "Validate every row without writing anything")
+	tenant := fs.String("tenant", "", "Tenant to write the records for (with a tenancy section)")
+	fs.Parse(args)
+
+	if *entity == "" || *file == "" {
+		fmt.Println("Usage: skema import --entity <Entity> --file <path> [--map header=field,...] [--dry-run]")
+		os.Exit(2)
+	}
+
+	columnMap, err := server.ParseColumnMap(*columns)
+	if err != nil {
+		log.Fatalf("Invalid --map: %v", err)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	_, srv := setup(*configPath)
+	srv = forTenant(srv, *tenant)
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	report, err := srv.Import(*entity, f, server.ImportOptions{
+		Format:  *format,
+		Columns: columnMap,
+		DryRun:  *dryRun,
+		Actor:   "skema import",
+	})
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	for _, failure := range report.Failed {
+		for _, e := range failure.Errors {
+			if e.Field != "" {
+				fmt.Printf("%s:%d: %s: %s\n", *file, failure.Line, e.Field, e.Message)
+			} else {
+				fmt.Printf("%s:%d: %s\n", *file, failure.Line, e.Message)
+			}
+		}
+	}
+
+	switch {
+	case len(report.Failed) > 0:
+		fmt.Printf("❌ %d of %d rows failed; nothing was imported\n", len(report.Failed), report.Total)
+		os.Exit(1)
+	case report.DryRun:
+		fmt.Printf("✅ %d rows are valid (dry run, nothing was imported)\n", report.Valid)
+	default:
+		fmt.Printf("✅ Imported %d %s records\n", report.Imported, *entity)
+	}
+}
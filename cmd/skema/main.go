@@ -12,13 +12,60 @@ import (
 	"github.com/iamajraj/skema/internal/server"
 )
 
+const databasePath = "skema.db"
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "seed":
+			runSeed(os.Args[2:])
+			return
+		case "fake":
+			runFake(os.Args[2:])
+			return
+		case "apikey":
+			runAPIKey(os.Args[2:])
+			return
+		}
+	}
+
 	configPath := flag.String("config", "skema.yml", "Path to the configuration file")
 	flag.Parse()
 
+	cfg, srv := setup(*configPath)
+
+	fmt.Printf("🚀 Starting %s...\n", cfg.Server.Name)
+
+	if len(cfg.Seeds) > 0 {
+		if cfg.Tenancy != nil {
+			// Seeds belong to a tenant, so they are applied per tenant
+			fmt.Println("🌱 Skipping seeds, apply them with skema seed --tenant <id>")
+		} else {
+			seed(srv, cfg.Seeds)
+		}
+	}
+
+	// Register Docs
+	docs.RegisterSwagger(srv.Router, cfg)
+
+	fmt.Printf("📚 ReDoc available at http://localhost:%d/docs\n", cfg.Server.Port)
+	fmt.Printf("🛠️  Swagger UI available at http://localhost:%d/swagger\n", cfg.Server.Port)
+
+	// Start server
+	if err := srv.Start(); err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+// setup loads the config, opens the database and builds the server shared
+// by the API and the CLI commands.
+func setup(configPath string) (*config.Config, *server.Server) {
 	// Check if config exists
-	if _, err := os.Stat(*configPath); os.IsNotExist(err) {
-		fmt.Printf("Config file %s not found.\n", *configPath)
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		fmt.Printf("Config file %s not found.\n", configPath)
 		fmt.Println("Please create a skema.yml file or specify one with --config.")
 		fmt.Println("Example skema.yml:")
 		fmt.Print(`
@@ -35,30 +82,34 @@ entities:
 	}
 
 	// Load config
-	cfg, err := config.LoadConfig(*configPath)
+	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
 
-	fmt.Printf("🚀 Starting %s...\n", cfg.Server.Name)
-
 	// Initialize DB
-	database, err := db.InitDB(cfg, "skema.db")
+	database, err := db.InitDB(cfg, databasePath)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
 	// Create server
-	srv := server.NewServer(cfg, database)
-
-	// Register Docs
-	docs.RegisterSwagger(srv.Router, cfg)
+	srv, err := server.NewServer(cfg, database)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
-	fmt.Printf("📚 ReDoc available at http://localhost:%d/docs\n", cfg.Server.Port)
-	fmt.Printf("🛠️  Swagger UI available at http://localhost:%d/swagger\n", cfg.Server.Port)
+	return cfg, srv
+}
 
-	// Start server
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Server failed: %v", err)
+// forTenant narrows srv to one tenant when --tenant is set.
+func forTenant(srv *server.Server, tenant string) *server.Server {
+	if tenant == "" {
+		return srv
+	}
+	srv, err := srv.Tenant(tenant)
+	if err != nil {
+		log.Fatalf("Invalid --tenant: %v", err)
 	}
+	return srv
 }
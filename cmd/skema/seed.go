@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/server"
+)
+
+// runSeed implements `skema seed [--file fixtures.yml]`. Without --file it
+// applies the seeds section of skema.yml.
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	configPath := fs.String("config", "skema.yml", "Path to the configuration file")
+	file := fs.String("file", "", "Fixture file with a seeds list (default: seeds in the config)")
+	tenant := fs.String("tenant", "", "Tenant to write the records for (with a tenancy section)")
+	fs.Parse(args)
+
+	cfg, srv := setup(*configPath)
+	srv = forTenant(srv, *tenant)
+
+	seeds := cfg.Seeds
+	if *file != "" {
+		var err error
+		seeds, err = config.LoadSeeds(*file)
+		if err != nil {
+			log.Fatalf("Error loading seeds: %v", err)
+		}
+	}
+	if len(seeds) == 0 {
+		fmt.Println("Nothing to seed.")
+		return
+	}
+
+	seed(srv, seeds)
+}
+
+func seed(srv *server.Server, seeds []config.SeedConfig) {
+	report, err := srv.Seed(seeds)
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, s := range seeds {
+		name := s.Entity
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		fmt.Printf("🌱 %s: %d created, %d updated, %d unchanged\n", name, report.Created[name], report.Updated[name], report.Unchanged[name])
+	}
+}
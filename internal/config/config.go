@@ -1,8 +1,62 @@
 package config
 
+import "time"
+
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Entities []EntityConfig `yaml:"entities"`
+	Server    ServerConfig     `yaml:"server"`
+	Entities  []EntityConfig   `yaml:"entities"`
+	Seeds     []SeedConfig     `yaml:"seeds,omitempty"`
+	Auth      *AuthConfig      `yaml:"auth,omitempty"`
+	Tenancy   *TenancyConfig   `yaml:"tenancy,omitempty"`
+	RateLimit *RateLimitConfig `yaml:"rate_limit,omitempty"`
+}
+
+// RateLimitConfig gives every client a token bucket that holds up to
+// burst requests and refills at requests per period. Rules set other
+// budgets for some entities and methods.
+type RateLimitConfig struct {
+	By          string          `yaml:"by,omitempty"`           // ip (default), api_key or user
+	IPHeader    string          `yaml:"ip_header,omitempty"`    // e.g. X-Forwarded-For behind a proxy
+	TrustedHops int             `yaml:"trusted_hops,omitempty"` // proxies appending to ip_header, default 1
+	Requests    int             `yaml:"requests,omitempty"`     // 0 leaves routes without a rule unlimited
+	Per         time.Duration   `yaml:"per,omitempty"`          // default 1m
+	Burst       int             `yaml:"burst,omitempty"`        // default requests
+	Persist     bool            `yaml:"persist,omitempty"`      // keep the buckets in SQLite across restarts
+	Rules       []RateLimitRule `yaml:"rules,omitempty"`
+}
+
+// RateLimitRule is the budget of the requests matching its entity and
+// methods. The first matching rule wins over the default budget.
+type RateLimitRule struct {
+	Entity   string        `yaml:"entity,omitempty"`  // default every route
+	Methods  []string      `yaml:"methods,omitempty"` // default every method
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per,omitempty"`
+	Burst    int           `yaml:"burst,omitempty"`
+}
+
+// TenancyConfig serves many tenants from one config. The tenant of a
+// request comes from a token claim, a header or the subdomain, in that
+// order.
+type TenancyConfig struct {
+	Header    string `yaml:"header,omitempty"`    // e.g. X-Tenant-ID
+	Subdomain bool   `yaml:"subdomain,omitempty"` // acme.api.example.com is tenant acme
+	Claim     string `yaml:"claim,omitempty"`     // token claim, default tenant_id
+	Mode      string `yaml:"mode,omitempty"`      // shared (default) or database
+	Column    string `yaml:"column,omitempty"`    // shared mode, default tenant_id
+	Directory string `yaml:"directory,omitempty"` // database mode, default tenants
+}
+
+// TenantColumn returns the column holding the tenant of each row when
+// tenants share tables, or "" otherwise.
+func (c *Config) TenantColumn() string {
+	if c.Tenancy == nil || (c.Tenancy.Mode != "" && c.Tenancy.Mode != "shared") {
+		return ""
+	}
+	if c.Tenancy.Column == "" {
+		return "tenant_id"
+	}
+	return c.Tenancy.Column
 }
 
 type ServerConfig struct {
@@ -10,10 +64,104 @@ type ServerConfig struct {
 	Name string `yaml:"name"`
 }
 
+// AuthConfig turns on authentication. Without it every route is open.
+type AuthConfig struct {
+	APIKeys     []APIKeyConfig `yaml:"api_keys,omitempty"`
+	APIKeysEnv  string         `yaml:"api_keys_env,omitempty"`  // env var holding a YAML/JSON list of keys
+	APIKeysFile string         `yaml:"api_keys_file,omitempty"` // YAML/JSON file with a list of keys
+	Header      string         `yaml:"header,omitempty"`        // default X-API-Key
+	// AnonymousScopes are granted to requests without credentials,
+	// e.g. [products:read] for a public catalog.
+	AnonymousScopes []string   `yaml:"anonymous_scopes,omitempty"`
+	JWT             *JWTConfig `yaml:"jwt,omitempty"`
+	// AdminRoles see and change every record of entities with an
+	// owner_field. Default [admin].
+	AdminRoles []string     `yaml:"admin_roles,omitempty"`
+	Users      *UsersConfig `yaml:"users,omitempty"`
+}
+
+// UsersConfig turns an entity into the user store behind POST /auth/login.
+// Its password field is hashed on write and never returned.
+type UsersConfig struct {
+	Entity        string        `yaml:"entity"`
+	UsernameField string        `yaml:"username_field,omitempty"` // default email
+	RolesField    string        `yaml:"roles_field,omitempty"`    // comma separated roles of a user
+	Scopes        []string      `yaml:"scopes,omitempty"`         // granted to logged in users, default *:read
+	Secret        string        `yaml:"secret,omitempty"`         // HMAC key signing the issued tokens
+	SecretEnv     string        `yaml:"secret_env,omitempty"`
+	AccessTTL     time.Duration `yaml:"access_ttl,omitempty"`  // default 15m
+	RefreshTTL    time.Duration `yaml:"refresh_ttl,omitempty"` // default 720h
+}
+
+// JWTConfig accepts `Authorization: Bearer` tokens signed with an HMAC
+// secret or with a key from a JWKS file.
+type JWTConfig struct {
+	Secret        string   `yaml:"secret,omitempty"`
+	SecretEnv     string   `yaml:"secret_env,omitempty"` // env var holding the secret
+	JWKSFile      string   `yaml:"jwks_file,omitempty"`
+	Issuer        string   `yaml:"issuer,omitempty"`
+	Audience      string   `yaml:"audience,omitempty"`
+	RequiredRoles []string `yaml:"required_roles,omitempty"` // tokens must carry all of them
+	RolesClaim    string   `yaml:"roles_claim,omitempty"`    // default roles, dots for nesting
+	ScopesClaim   string   `yaml:"scopes_claim,omitempty"`   // default scope
+	RequireExp    bool     `yaml:"require_exp,omitempty"`    // reject tokens without an exp claim
+	// DefaultScopes are granted to every valid token on top of the scopes
+	// it carries.
+	DefaultScopes []string `yaml:"default_scopes,omitempty"`
+}
+
+// APIKeyConfig declares one API key. Scopes look like products:read or
+// orders:write; * matches any entity or access.
+type APIKeyConfig struct {
+	Name   string   `yaml:"name"`
+	Key    string   `yaml:"key,omitempty"`  // plaintext, hashed when the server starts
+	Hash   string   `yaml:"hash,omitempty"` // sha256:<hex>, see `skema apikey`
+	Scopes []string `yaml:"scopes"`
+	Roles  []string `yaml:"roles,omitempty"`  // checked against entity permissions
+	Tenant string   `yaml:"tenant,omitempty"` // the only tenant the key may use
+}
+
 type EntityConfig struct {
-	Name      string           `yaml:"name"`
-	Fields    []FieldConfig    `yaml:"fields"`
-	Relations []RelationConfig `yaml:"relations"`
+	Name          string           `yaml:"name"`
+	Fields        []FieldConfig    `yaml:"fields"`
+	Relations     []RelationConfig `yaml:"relations"`
+	UnknownFields string           `yaml:"unknown_fields,omitempty"` // reject (default), strip
+	Rules         []RuleConfig     `yaml:"rules,omitempty"`
+	SoftDelete    bool             `yaml:"soft_delete,omitempty"`
+	History       bool             `yaml:"history,omitempty"`
+	Versioned     bool             `yaml:"versioned,omitempty"` // adds a version column used for ETags
+	// OwnerField ties each record to the caller that created it. Callers
+	// only see and change their own records unless they hold an admin role.
+	OwnerField string `yaml:"owner_field,omitempty"`
+	// Permissions maps roles to what they may do with the entity. Entities
+	// without permissions are open to every authorized caller.
+	Permissions map[string]PermissionConfig `yaml:"permissions,omitempty"`
+}
+
+// PermissionConfig lists the operations (list, get, create, update, delete
+// or *) a role may perform, and optionally the fields it may read and
+// write. The role * applies to every caller.
+type PermissionConfig struct {
+	Operations []string `yaml:"operations"`
+	Read       []string `yaml:"read,omitempty"`  // readable fields, default all
+	Write      []string `yaml:"write,omitempty"` // writable fields, default all
+}
+
+// RuleConfig is an entity-level invariant checked on create and update,
+// e.g. `end_date >= start_date`. See internal/expr for the syntax.
+type RuleConfig struct {
+	Expr    string `yaml:"expr"`
+	Message string `yaml:"message"`
+}
+
+// SeedConfig is a set of fixture records for one entity. A record can be
+// named with `_ref: alice` and referenced from later records as "@alice".
+// Key lists the natural key fields used to find records seeded before;
+// it defaults to the entity's unique fields.
+type SeedConfig struct {
+	Entity  string                   `yaml:"entity"`
+	Key     []string                 `yaml:"key,omitempty"`
+	Records []map[string]interface{} `yaml:"records"`
 }
 
 type RelationConfig struct {
@@ -23,12 +171,17 @@ type RelationConfig struct {
 }
 
 type FieldConfig struct {
-	Name     string `yaml:"name"`
-	Type     string `yaml:"type"` // string, int, bool, text, float
-	Required bool   `yaml:"required"`
-	Unique   bool   `yaml:"unique"`
-	Min      *int   `yaml:"min,omitempty"`
-	Max      *int   `yaml:"max,omitempty"`
-	Pattern  string `yaml:"pattern,omitempty"`
-	Format   string `yaml:"format,omitempty"` // email, uuid, etc.
+	Name      string `yaml:"name"`
+	Type      string `yaml:"type"` // string, int, bool, text, float, password
+	Required  bool   `yaml:"required"`
+	Unique    bool   `yaml:"unique"`
+	Min       *int   `yaml:"min,omitempty"`
+	Max       *int   `yaml:"max,omitempty"`
+	MinLength *int   `yaml:"min_length,omitempty"` // string, text and password only
+	MaxLength *int   `yaml:"max_length,omitempty"` // string, text and password only
+	Pattern   string `yaml:"pattern,omitempty"`
+	Format    string `yaml:"format,omitempty"`     // see internal/formats for the registry
+	ReadOnly  bool   `yaml:"read_only,omitempty"`  // ignored when clients write it
+	WriteOnly bool   `yaml:"write_only,omitempty"` // never returned, e.g. secrets
+	Hidden    bool   `yaml:"hidden,omitempty"`     // neither returned nor written by clients
 }
@@ -27,3 +27,20 @@ func LoadConfig(path string) (*Config, error) {
 
 	return &cfg, nil
 }
+
+// LoadSeeds reads a fixture file with a top-level `seeds:` list, in the
+// same shape as the seeds section of skema.yml. JSON files work as well.
+func LoadSeeds(path string) ([]SeedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Seeds []SeedConfig `yaml:"seeds"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Seeds, nil
+}
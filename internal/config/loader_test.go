@@ -54,3 +54,28 @@ entities: []
 	assert.Equal(t, 8080, cfg.Server.Port)
 	assert.Equal(t, "Skema API", cfg.Server.Name)
 }
+
+func TestLoadSeeds(t *testing.T) {
+	yamlContent := `
+seeds:
+  - entity: User
+    key: [email]
+    records:
+      - _ref: alice
+        email: alice@example.com
+`
+	tmpfile, err := os.CreateTemp("", "skema_test_seeds_*.yml")
+	assert.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.Write([]byte(yamlContent))
+	assert.NoError(t, err)
+	tmpfile.Close()
+
+	seeds, err := LoadSeeds(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Len(t, seeds, 1)
+	assert.Equal(t, "User", seeds[0].Entity)
+	assert.Equal(t, []string{"email"}, seeds[0].Key)
+	assert.Equal(t, "alice", seeds[0].Records[0]["_ref"])
+}
@@ -15,22 +15,47 @@ func InitDB(cfg *config.Config, path string) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	tenantColumn := cfg.TenantColumn()
 	for _, entity := range cfg.Entities {
-		err := createTable(db, entity)
+		err := createTable(db, entity, tenantColumn)
 		if err != nil {
 			return nil, err
 		}
+		if entity.History {
+			if err := createHistoryTable(db, entity); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cfg.Auth != nil && cfg.Auth.Users != nil {
+		if err := createRevokedTokensTable(db); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.RateLimit != nil && cfg.RateLimit.Persist {
+		if err := createRateLimitTable(db); err != nil {
+			return nil, err
+		}
 	}
 
 	return db, nil
 }
 
-func createTable(db *gorm.DB, entity config.EntityConfig) error {
+func createTable(db *gorm.DB, entity config.EntityConfig, tenantColumn string) error {
 	tableName := strings.ToLower(entity.Name) + "s"
 
 	// Check if table exists
 	if db.Migrator().HasTable(tableName) {
-		return nil
+		// Options turned on after the table was created add their columns
+		for _, col := range optionColumns(entity, tenantColumn) {
+			if !db.Migrator().HasColumn(tableName, col.name) {
+				if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, col.name, col.def)).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return createTenantIndexes(db, entity, tableName, tenantColumn)
 	}
 
 	var columns []string
@@ -57,13 +82,24 @@ func createTable(db *gorm.DB, entity config.EntityConfig) error {
 		if field.Required {
 			colDef += " NOT NULL"
 		}
-		if field.Unique {
+		// Tenants sharing the table are only unique among themselves
+		if field.Unique && tenantColumn == "" {
 			colDef += " UNIQUE"
 		}
+		// Password columns hold hashes, their lengths are checked before hashing
+		if field.MinLength != nil && field.Type != "password" {
+			colDef += fmt.Sprintf(" CHECK (length(%s) >= %d)", field.Name, *field.MinLength)
+		}
+		if field.MaxLength != nil && field.Type != "password" {
+			colDef += fmt.Sprintf(" CHECK (length(%s) <= %d)", field.Name, *field.MaxLength)
+		}
 		columns = append(columns, colDef)
 	}
 
 	columns = append(columns, "created_at DATETIME", "updated_at DATETIME")
+	for _, col := range optionColumns(entity, tenantColumn) {
+		columns = append(columns, col.name+" "+col.def)
+	}
 
 	// Add Foreign Keys
 	for _, rel := range entity.Relations {
@@ -74,5 +110,100 @@ func createTable(db *gorm.DB, entity config.EntityConfig) error {
 	}
 
 	query := fmt.Sprintf("CREATE TABLE %s (%s)", tableName, strings.Join(columns, ", "))
-	return db.Exec(query).Error
+	if err := db.Exec(query).Error; err != nil {
+		return err
+	}
+	return createTenantIndexes(db, entity, tableName, tenantColumn)
+}
+
+// createTenantIndexes indexes the tenant column of shared tables and makes
+// unique fields unique per tenant.
+func createTenantIndexes(db *gorm.DB, entity config.EntityConfig, tableName, tenantColumn string) error {
+	if tenantColumn == "" {
+		return nil
+	}
+	indexes := []string{fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s)", tableName, tenantColumn, tableName, tenantColumn)}
+	for _, field := range entity.Fields {
+		if field.Unique {
+			indexes = append(indexes, fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS idx_%s_%s_%s ON %s (%s, %s)", tableName, tenantColumn, field.Name, tableName, tenantColumn, field.Name))
+		}
+	}
+	for _, index := range indexes {
+		if err := db.Exec(index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type column struct {
+	name string
+	def  string
+}
+
+// optionColumns lists the system columns added by entity options and by
+// shared-table tenancy.
+func optionColumns(entity config.EntityConfig, tenantColumn string) []column {
+	var columns []column
+	if tenantColumn != "" {
+		columns = append(columns, column{tenantColumn, "TEXT"})
+	}
+	if entity.SoftDelete {
+		columns = append(columns, column{"deleted_at", "DATETIME"})
+	}
+	if entity.Versioned {
+		columns = append(columns, column{"version", "INTEGER NOT NULL DEFAULT 1"})
+	}
+	return columns
+}
+
+// createHistoryTable creates <table>_history, the change log written by the
+// server for entities with `history: true`.
+func createHistoryTable(db *gorm.DB, entity config.EntityConfig) error {
+	tableName := strings.ToLower(entity.Name) + "s_history"
+
+	if db.Migrator().HasTable(tableName) {
+		return nil
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		record_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		old_values TEXT,
+		new_values TEXT,
+		actor TEXT,
+		changed_at DATETIME NOT NULL
+	)`, tableName)
+	if err := db.Exec(query).Error; err != nil {
+		return err
+	}
+
+	return db.Exec(fmt.Sprintf("CREATE INDEX idx_%s_record ON %s (record_id, changed_at)", tableName, tableName)).Error
+}
+
+// createRevokedTokensTable creates auth_revoked_tokens, where the server
+// keeps the ids of login tokens revoked by logout or refresh until they
+// expire.
+func createRevokedTokensTable(db *gorm.DB) error {
+	if db.Migrator().HasTable("auth_revoked_tokens") {
+		return nil
+	}
+	return db.Exec(`CREATE TABLE auth_revoked_tokens (
+		jti TEXT PRIMARY KEY,
+		expires_at DATETIME NOT NULL
+	)`).Error
+}
+
+// createRateLimitTable creates rate_limit_buckets, where the server saves
+// the token buckets of rate limited clients so they survive restarts.
+func createRateLimitTable(db *gorm.DB) error {
+	if db.Migrator().HasTable("rate_limit_buckets") {
+		return nil
+	}
+	return db.Exec(`CREATE TABLE rate_limit_buckets (
+		client TEXT PRIMARY KEY,
+		tokens REAL NOT NULL,
+		updated_at DATETIME NOT NULL
+	)`).Error
 }
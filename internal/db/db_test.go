@@ -60,3 +60,47 @@ func TestForeignKeys(t *testing.T) {
 	assert.True(t, database.Migrator().HasTable("users"))
 	assert.True(t, database.Migrator().HasTable("profiles"))
 }
+
+func TestLengthCheckConstraints(t *testing.T) {
+	dbPath := "test_length.db"
+	defer os.Remove(dbPath)
+
+	minLen, maxLen := 3, 5
+	cfg := &config.Config{
+		Entities: []config.EntityConfig{
+			{
+				Name: "Tag",
+				Fields: []config.FieldConfig{
+					{Name: "label", Type: "string", MinLength: &minLen, MaxLength: &maxLen},
+				},
+			},
+		},
+	}
+
+	database, err := InitDB(cfg, dbPath)
+	assert.NoError(t, err)
+
+	assert.NoError(t, database.Exec("INSERT INTO tags (label) VALUES (?)", "abcd").Error)
+	assert.Error(t, database.Exec("INSERT INTO tags (label) VALUES (?)", "ab").Error)
+	assert.Error(t, database.Exec("INSERT INTO tags (label) VALUES (?)", "abcdef").Error)
+}
+
+func TestSoftDeleteColumn(t *testing.T) {
+	dbPath := "test_soft_delete.db"
+	defer os.Remove(dbPath)
+
+	cfg := &config.Config{
+		Entities: []config.EntityConfig{
+			{Name: "Note", Fields: []config.FieldConfig{{Name: "body", Type: "text"}}},
+		},
+	}
+	database, err := InitDB(cfg, dbPath)
+	assert.NoError(t, err)
+	assert.False(t, database.Migrator().HasColumn("notes", "deleted_at"))
+
+	// Turning soft_delete on later adds the column to the existing table
+	cfg.Entities[0].SoftDelete = true
+	database, err = InitDB(cfg, dbPath)
+	assert.NoError(t, err)
+	assert.True(t, database.Migrator().HasColumn("notes", "deleted_at"))
+}
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/formats"
 )
 
 func RegisterSwagger(r *chi.Mux, cfg *config.Config) {
@@ -99,18 +101,49 @@ func generateOpenAPI(cfg *config.Config) map[string]interface{} {
 
 		tags = append(tags, map[string]interface{}{
 			"name":        name,
-			"description": "Operations for " + name,
+			"description": "Operations for " + name + ownerDescription(entity) + permissionsDescription(entity),
 		})
 
 		// Schema definition
 		schemaProperties := make(map[string]interface{})
-		schemaProperties["id"] = map[string]interface{}{"type": "integer"}
+		schemaProperties["id"] = map[string]interface{}{"type": "integer", "readOnly": true}
 		for _, field := range entity.Fields {
+			if field.Hidden {
+				continue
+			}
 			prop := map[string]interface{}{"type": mapType(field.Type)}
+			if f, ok := formats.Lookup(field.Format); ok && f.OpenAPI != "" {
+				prop["format"] = f.OpenAPI
+			}
+			if field.MinLength != nil {
+				prop["minLength"] = *field.MinLength
+			}
+			if field.MaxLength != nil {
+				prop["maxLength"] = *field.MaxLength
+			}
+			if field.Type == "password" {
+				// Stored as a hash and never returned
+				prop["format"] = "password"
+			}
+			if field.ReadOnly {
+				prop["readOnly"] = true
+			}
+			if field.WriteOnly || field.Type == "password" {
+				prop["writeOnly"] = true
+			}
 			schemaProperties[field.Name] = prop
 		}
-		schemaProperties["created_at"] = map[string]interface{}{"type": "string", "format": "date-time"}
-		schemaProperties["updated_at"] = map[string]interface{}{"type": "string", "format": "date-time"}
+		if column := cfg.TenantColumn(); column != "" {
+			schemaProperties[column] = map[string]interface{}{"type": "string", "readOnly": true}
+		}
+		schemaProperties["created_at"] = map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true}
+		schemaProperties["updated_at"] = map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true}
+		if entity.Versioned {
+			schemaProperties["version"] = map[string]interface{}{"type": "integer", "readOnly": true}
+		}
+		if entity.SoftDelete {
+			schemaProperties["deleted_at"] = map[string]interface{}{"type": "string", "format": "date-time", "nullable": true, "readOnly": true}
+		}
 
 		schemas[name] = map[string]interface{}{
 			"type":       "object",
@@ -142,15 +175,35 @@ func generateOpenAPI(cfg *config.Config) map[string]interface{} {
 			})
 		}
 
+		if entity.SoftDelete {
+			collectionParams = append(collectionParams, map[string]interface{}{
+				"name":        "trashed",
+				"in":          "query",
+				"schema":      map[string]interface{}{"type": "string", "enum": []string{"only", "with"}},
+				"description": "Show only soft-deleted records (only) or include them (with)",
+			})
+		}
+
 		// Dynamic filters
+		var filterParams []interface{}
 		for _, field := range entity.Fields {
-			collectionParams = append(collectionParams, map[string]interface{}{
+			if field.Type == "password" || field.WriteOnly || field.Hidden {
+				continue
+			}
+			filterParams = append(filterParams, map[string]interface{}{
 				"name":        field.Name,
 				"in":          "query",
 				"schema":      map[string]interface{}{"type": mapType(field.Type)},
 				"description": "Filter by " + field.Name,
 			})
 		}
+		collectionParams = append(collectionParams, filterParams...)
+		collectionParams = append(collectionParams, map[string]interface{}{
+			"name":        "format",
+			"in":          "query",
+			"schema":      map[string]interface{}{"type": "string", "enum": []string{"json", "csv", "ndjson"}},
+			"description": "Stream all matching rows as CSV or NDJSON instead of a JSON page (also selected by the Accept header)",
+		})
 
 		paths[collectionPath] = map[string]interface{}{
 			"get": map[string]interface{}{
@@ -183,13 +236,20 @@ func generateOpenAPI(cfg *config.Config) map[string]interface{} {
 									},
 								},
 							},
+							"text/csv": map[string]interface{}{
+								"schema": map[string]interface{}{"type": "string"},
+							},
+							"application/x-ndjson": map[string]interface{}{
+								"schema": map[string]interface{}{"$ref": "#/components/schemas/" + name},
+							},
 						},
 					},
 				},
 			},
 			"post": map[string]interface{}{
-				"tags":    []string{name},
-				"summary": "Create a new " + lowerName,
+				"tags":        []string{name},
+				"summary":     "Create a new " + lowerName,
+				"description": createDescription(entity),
 				"requestBody": map[string]interface{}{
 					"required": true,
 					"content": map[string]interface{}{
@@ -217,6 +277,130 @@ func generateOpenAPI(cfg *config.Config) map[string]interface{} {
 			},
 		}
 
+		// Aggregation
+		var fieldNames, numericFields []string
+		for _, field := range entity.Fields {
+			if field.Type == "password" || field.WriteOnly || field.Hidden {
+				continue
+			}
+			fieldNames = append(fieldNames, field.Name)
+			if field.Type == "int" || field.Type == "float" {
+				numericFields = append(numericFields, field.Name)
+			}
+		}
+		aggregateParams := []interface{}{
+			map[string]interface{}{"name": "group_by", "in": "query", "schema": map[string]interface{}{"type": "string"}, "description": "Comma separated fields to group by: " + strings.Join(fieldNames, ", ")},
+			map[string]interface{}{"name": "count", "in": "query", "schema": map[string]interface{}{"type": "string"}, "description": "`*` to count rows, or fields to count non-null values of"},
+		}
+		for _, fn := range []string{"sum", "avg", "min", "max"} {
+			aggregateParams = append(aggregateParams, map[string]interface{}{
+				"name":        fn,
+				"in":          "query",
+				"schema":      map[string]interface{}{"type": "string"},
+				"description": "Comma separated numeric fields: " + strings.Join(numericFields, ", "),
+			})
+		}
+		paths[collectionPath+"/_aggregate"] = map[string]interface{}{
+			"get": map[string]interface{}{
+				"tags":        []string{name},
+				"summary":     "Aggregate " + lowerName + "s",
+				"description": "Returns one row per group with keys like `count`, `sum_<field>` and `avg_<field>`. Accepts the same filters as the list endpoint; counts rows when no aggregate is given.",
+				"parameters":  append(aggregateParams, filterParams...),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Aggregated rows",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"success": map[string]interface{}{"type": "boolean"},
+										"data": map[string]interface{}{
+											"type":  "array",
+											"items": map[string]interface{}{"type": "object", "additionalProperties": true},
+										},
+									},
+								},
+							},
+						},
+					},
+					"400": map[string]interface{}{"description": "Unknown or non-numeric field"},
+				},
+			},
+		}
+
+		paths[collectionPath+"/_import"] = map[string]interface{}{
+			"post": map[string]interface{}{
+				"tags":        []string{name},
+				"summary":     "Import " + lowerName + "s",
+				"description": "Creates records from a CSV file (`text/csv`, with a header line) or a JSON array. Every row is validated like a regular create; if any row fails, nothing is imported and the failed rows are reported with their line numbers.",
+				"parameters": []interface{}{
+					map[string]interface{}{"name": "columns", "in": "query", "schema": map[string]interface{}{"type": "string"}, "description": "Map input columns to fields, e.g. `Product Name=name,SKU=-` (`-` ignores a column)"},
+					map[string]interface{}{"name": "dry_run", "in": "query", "schema": map[string]interface{}{"type": "boolean"}, "description": "Validate every row without writing anything"},
+				},
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content": map[string]interface{}{
+						"text/csv": map[string]interface{}{
+							"schema": map[string]interface{}{"type": "string"},
+						},
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type":  "array",
+								"items": map[string]interface{}{"$ref": "#/components/schemas/" + name},
+							},
+						},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{"description": "Import report with `total`, `valid` and `imported` counts"},
+					"400": map[string]interface{}{"description": "Malformed input or failed rows (`error.rows` lists `line` and `errors` per row)"},
+				},
+			},
+		}
+
+		facetParams := []interface{}{
+			map[string]interface{}{"name": "fields", "in": "query", "required": true, "schema": map[string]interface{}{"type": "string"}, "description": "Comma separated fields to facet on: " + strings.Join(fieldNames, ", ")},
+		}
+		paths[collectionPath+"/_facets"] = map[string]interface{}{
+			"get": map[string]interface{}{
+				"tags":        []string{name},
+				"summary":     "Facet counts for " + lowerName + "s",
+				"description": "Returns the distinct values of each field with their counts under the same filters as the list endpoint. Buckets of belongs_to fields include the `label` of the related record.",
+				"parameters":  append(facetParams, filterParams...),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Buckets per field",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"success": map[string]interface{}{"type": "boolean"},
+										"data": map[string]interface{}{
+											"type": "object",
+											"additionalProperties": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"value": map[string]interface{}{},
+														"count": map[string]interface{}{"type": "integer"},
+														"label": map[string]interface{}{"type": "string"},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					"400": map[string]interface{}{"description": "Missing or unknown field"},
+				},
+			},
+		}
+
 		paths[itemPath] = map[string]interface{}{
 			"parameters": []interface{}{
 				map[string]interface{}{
@@ -287,20 +471,455 @@ func generateOpenAPI(cfg *config.Config) map[string]interface{} {
 				},
 			},
 		}
+
+		// Partial updates and conditional requests
+		item := paths[itemPath].(map[string]interface{})
+		itemGet := item["get"].(map[string]interface{})
+		put := item["put"].(map[string]interface{})
+		item["patch"] = map[string]interface{}{
+			"tags":        []string{name},
+			"summary":     "Partially update " + lowerName + " by ID",
+			"requestBody": put["requestBody"],
+			"responses": map[string]interface{}{
+				"200": put["responses"].(map[string]interface{})["200"],
+			},
+		}
+
+		etagHeader := map[string]interface{}{
+			"ETag": map[string]interface{}{
+				"description": "Current version of the record",
+				"schema":      map[string]interface{}{"type": "string"},
+			},
+		}
+		itemGet["parameters"] = []interface{}{
+			map[string]interface{}{
+				"name":        "If-None-Match",
+				"in":          "header",
+				"schema":      map[string]interface{}{"type": "string"},
+				"description": "Return 304 if the record still has this ETag",
+			},
+		}
+		itemGet["responses"].(map[string]interface{})["200"].(map[string]interface{})["headers"] = etagHeader
+		itemGet["responses"].(map[string]interface{})["304"] = map[string]interface{}{"description": "Not modified"}
+
+		for _, method := range []string{"put", "patch", "delete"} {
+			op := item[method].(map[string]interface{})
+			op["parameters"] = []interface{}{
+				map[string]interface{}{
+					"name":        "If-Match",
+					"in":          "header",
+					"schema":      map[string]interface{}{"type": "string"},
+					"description": "Only apply the change if the record still has this ETag",
+				},
+			}
+			responses := op["responses"].(map[string]interface{})
+			responses["412"] = map[string]interface{}{"description": "The record was modified since the given ETag"}
+			if success, isMap := responses["200"].(map[string]interface{}); isMap {
+				updated := make(map[string]interface{}, len(success)+1)
+				for k, v := range success {
+					updated[k] = v
+				}
+				updated["headers"] = etagHeader
+				responses["200"] = updated
+			}
+		}
+
+		if entity.History {
+			itemGet["parameters"] = append(itemGet["parameters"].([]interface{}), map[string]interface{}{
+				"name":        "as_of",
+				"in":          "query",
+				"schema":      map[string]interface{}{"type": "string", "format": "date-time"},
+				"description": "Return the record as it was at this time, reconstructed from its history",
+			})
+
+			paths[itemPath+"/history"] = map[string]interface{}{
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":     "id",
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]interface{}{"type": "integer"},
+					},
+				},
+				"get": map[string]interface{}{
+					"tags":    []string{name},
+					"summary": "Change history of a " + lowerName,
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Changes, oldest first",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{"type": "boolean"},
+											"data": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"id":         map[string]interface{}{"type": "integer"},
+														"record_id":  map[string]interface{}{"type": "integer"},
+														"action":     map[string]interface{}{"type": "string", "enum": []string{"create", "update", "delete", "restore"}},
+														"old_values": map[string]interface{}{"$ref": "#/components/schemas/" + name},
+														"new_values": map[string]interface{}{"$ref": "#/components/schemas/" + name},
+														"actor":      map[string]interface{}{"type": "string"},
+														"changed_at": map[string]interface{}{"type": "string", "format": "date-time"},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		if entity.SoftDelete {
+			paths[itemPath+"/restore"] = map[string]interface{}{
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":     "id",
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]interface{}{"type": "integer"},
+					},
+				},
+				"post": map[string]interface{}{
+					"tags":    []string{name},
+					"summary": "Restore a deleted " + lowerName,
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Restored",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{"type": "boolean"},
+											"data":    map[string]interface{}{"$ref": "#/components/schemas/" + name},
+										},
+									},
+								},
+							},
+						},
+						"404": map[string]interface{}{"description": "No deleted " + lowerName + " with this ID"},
+					},
+				},
+			}
+		}
+	}
+
+	paths["/_batch"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"tags":        []string{"Batch"},
+			"summary":     "Run several operations in one transaction",
+			"description": "Operations run in order and are all rolled back if any of them fails. Paths and bodies can reference fields of earlier results, e.g. \"$0.id\".",
+			"requestBody": map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"operations": map[string]interface{}{
+									"type": "array",
+									"items": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"method": map[string]interface{}{"type": "string", "enum": []string{"GET", "POST", "PUT", "PATCH", "DELETE"}},
+											"path":   map[string]interface{}{"type": "string"},
+											"body":   map[string]interface{}{"type": "object"},
+										},
+										"required": []string{"method", "path"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "All operations succeeded",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"success": map[string]interface{}{"type": "boolean"},
+									"data": map[string]interface{}{
+										"type": "array",
+										"items": map[string]interface{}{
+											"type": "object",
+											"properties": map[string]interface{}{
+												"status": map[string]interface{}{"type": "integer"},
+												"body":   map[string]interface{}{"type": "object"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	tags = append(tags, map[string]interface{}{
+		"name":        "Batch",
+		"description": "Atomic multi-operation requests",
+	})
+
+	if cfg.Auth != nil && cfg.Auth.Users != nil {
+		addAccountPaths(paths, cfg)
+		tags = append(tags, map[string]interface{}{
+			"name":        "Auth",
+			"description": "Login with the " + cfg.Auth.Users.Entity + " records",
+		})
 	}
 
 	components["schemas"] = schemas
 
-	return map[string]interface{}{
-		"openapi": "3.0.0",
-		"info": map[string]interface{}{
-			"title":   cfg.Server.Name,
-			"version": "1.0.0",
-		},
+	info := map[string]interface{}{
+		"title":   cfg.Server.Name,
+		"version": "1.0.0",
+	}
+	var description []string
+	for _, d := range []string{tenancyDescription(cfg), rateLimitDescription(cfg)} {
+		if d != "" {
+			description = append(description, d)
+		}
+	}
+	if len(description) > 0 {
+		info["description"] = strings.Join(description, "\n\n")
+	}
+
+	spec := map[string]interface{}{
+		"openapi":    "3.0.0",
+		"info":       info,
 		"tags":       tags,
 		"paths":      paths,
 		"components": components,
 	}
+
+	if cfg.Auth != nil {
+		header := cfg.Auth.Header
+		if header == "" {
+			header = "X-API-Key"
+		}
+		schemes := map[string]interface{}{
+			"ApiKeyAuth": map[string]interface{}{
+				"type":        "apiKey",
+				"in":          "header",
+				"name":        header,
+				"description": "Keys carry scopes like `products:read` or `orders:write`.",
+			},
+		}
+		security := []interface{}{map[string]interface{}{"ApiKeyAuth": []string{}}}
+		if cfg.Auth.JWT != nil || cfg.Auth.Users != nil {
+			schemes["BearerAuth"] = map[string]interface{}{
+				"type":         "http",
+				"scheme":       "bearer",
+				"bearerFormat": "JWT",
+			}
+			security = append(security, map[string]interface{}{"BearerAuth": []string{}})
+		}
+		components["securitySchemes"] = schemes
+		if len(cfg.Auth.AnonymousScopes) > 0 {
+			// Some operations also work without credentials
+			security = append(security, map[string]interface{}{})
+		}
+		spec["security"] = security
+	}
+
+	return spec
+}
+
+// createDescription documents the nested payloads accepted on create.
+func createDescription(entity config.EntityConfig) string {
+	var nested []string
+	for _, rel := range entity.Relations {
+		if rel.Type == "belongs_to" {
+			nested = append(nested, fmt.Sprintf("`%s` (object, fills %s)", strings.ToLower(rel.Entity), rel.Field))
+		} else {
+			nested = append(nested, fmt.Sprintf("`%ss` (array)", strings.ToLower(rel.Entity)))
+		}
+	}
+	if len(nested) == 0 {
+		return ""
+	}
+	return "Related records can be created in the same transaction by nesting them: " + strings.Join(nested, ", ") + "."
+}
+
+// addAccountPaths documents the login, refresh and logout endpoints of the
+// user store.
+func addAccountPaths(paths map[string]interface{}, cfg *config.Config) {
+	users := cfg.Auth.Users
+	username := users.UsernameField
+	if username == "" {
+		username = "email"
+	}
+	password := "password"
+	for _, entity := range cfg.Entities {
+		if strings.EqualFold(entity.Name, users.Entity) {
+			for _, field := range entity.Fields {
+				if field.Type == "password" {
+					password = field.Name
+				}
+			}
+		}
+	}
+	tokens := map[string]interface{}{
+		"description": "A new token pair",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"success": map[string]interface{}{"type": "boolean"},
+						"data": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"access_token":  map[string]interface{}{"type": "string"},
+								"refresh_token": map[string]interface{}{"type": "string"},
+								"token_type":    map[string]interface{}{"type": "string"},
+								"expires_in":    map[string]interface{}{"type": "integer"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	refreshBody := map[string]interface{}{
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"refresh_token": map[string]interface{}{"type": "string"}},
+				},
+			},
+		},
+	}
+
+	paths["/auth/login"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"tags":        []string{"Auth"},
+			"summary":     "Log in",
+			"description": "Checks the " + username + " and password of a " + users.Entity + " and returns an access token for `Authorization: Bearer` and a refresh token.",
+			"requestBody": map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								username: map[string]interface{}{"type": "string"},
+								password: map[string]interface{}{"type": "string", "format": "password"},
+							},
+						},
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": tokens,
+				"401": map[string]interface{}{"description": "Invalid credentials"},
+			},
+		},
+	}
+	paths["/auth/refresh"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"tags":        []string{"Auth"},
+			"summary":     "Refresh tokens",
+			"description": "Exchanges a refresh token for a new token pair. Each refresh token can be used once.",
+			"requestBody": refreshBody,
+			"responses": map[string]interface{}{
+				"200": tokens,
+				"401": map[string]interface{}{"description": "Invalid, expired or revoked refresh token"},
+			},
+		},
+	}
+	paths["/auth/logout"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"tags":        []string{"Auth"},
+			"summary":     "Log out",
+			"description": "Revokes the access token sent in the Authorization header and the refresh token in the body.",
+			"requestBody": refreshBody,
+			"responses": map[string]interface{}{
+				"204": map[string]interface{}{"description": "Tokens revoked"},
+			},
+		},
+	}
+}
+
+// tenancyDescription explains how requests pick their tenant.
+func tenancyDescription(cfg *config.Config) string {
+	t := cfg.Tenancy
+	if t == nil {
+		return ""
+	}
+	var sources []string
+	if t.Header != "" {
+		sources = append(sources, fmt.Sprintf("the `%s` header", t.Header))
+	}
+	if t.Subdomain {
+		sources = append(sources, "the subdomain")
+	}
+	claim := t.Claim
+	if claim == "" {
+		claim = "tenant_id"
+	}
+	description := fmt.Sprintf("Every request works on the data of one tenant, taken from the `%s` claim of the bearer token", claim)
+	if len(sources) > 0 {
+		description += " or else from " + strings.Join(sources, " or ")
+	}
+	return description + ". Requests without a tenant get 400."
+}
+
+// rateLimitDescription explains the rate limit headers and responses.
+func rateLimitDescription(cfg *config.Config) string {
+	if cfg.RateLimit == nil {
+		return ""
+	}
+	return "Requests are rate limited per client. Limited routes answer with `RateLimit-Limit`, `RateLimit-Remaining` and `RateLimit-Reset` headers, and with `429` and a `Retry-After` header once the budget is spent."
+}
+
+// ownerDescription explains the per-caller scoping of owned entities.
+func ownerDescription(entity config.EntityConfig) string {
+	if entity.OwnerField == "" {
+		return ""
+	}
+	return fmt.Sprintf(". Callers only see and change their own records; `%s` is set to the caller on create", entity.OwnerField)
+}
+
+// permissionsDescription summarizes what each role may do on the entity.
+func permissionsDescription(entity config.EntityConfig) string {
+	roles := make([]string, 0, len(entity.Permissions))
+	for role := range entity.Permissions {
+		roles = append(roles, role)
+	}
+	if len(roles) == 0 {
+		return ""
+	}
+	sort.Strings(roles)
+
+	lines := make([]string, len(roles))
+	for i, role := range roles {
+		perm := entity.Permissions[role]
+		line := fmt.Sprintf("`%s`: %s", role, strings.Join(perm.Operations, ", "))
+		if len(perm.Read) > 0 {
+			line += "; reads " + strings.Join(perm.Read, ", ")
+		}
+		if len(perm.Write) > 0 {
+			line += "; writes " + strings.Join(perm.Write, ", ")
+		}
+		lines[i] = line
+	}
+	return ". Permissions by role (other callers get 403): " + strings.Join(lines, " | ")
 }
 
 func mapType(t string) string {
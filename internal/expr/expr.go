@@ -0,0 +1,650 @@
+// Package expr implements the small expression language used by entity
+// `rules:` in skema.yml, e.g. `end_date >= start_date` or
+// `status == 'done' implies completed_at != null`.
+//
+// Expressions can only read the values they are given: there are no
+// assignments or loops, and the only callable function is the built-in
+// len(), so evaluating a rule cannot affect anything outside its result.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Program is a compiled expression.
+type Program struct {
+	source string
+	root   node
+	idents []string
+}
+
+// Compile parses src into a Program.
+func Compile(src string) (*Program, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseImplies()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	seen := map[string]bool{}
+	collectIdents(root, seen)
+	idents := make([]string, 0, len(seen))
+	for name := range seen {
+		idents = append(idents, name)
+	}
+	sort.Strings(idents)
+
+	return &Program{source: src, root: root, idents: idents}, nil
+}
+
+// String returns the source the program was compiled from.
+func (p *Program) String() string {
+	return p.source
+}
+
+// Identifiers lists the variable names referenced by the program.
+func (p *Program) Identifiers() []string {
+	return p.idents
+}
+
+// Eval evaluates the program against env. Missing variables are null.
+func (p *Program) Eval(env map[string]interface{}) (interface{}, error) {
+	return p.root.eval(env)
+}
+
+// EvalBool evaluates the program and requires a boolean result.
+func (p *Program) EvalBool(env map[string]interface{}) (bool, error) {
+	val, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q must evaluate to a boolean, got %s", p.source, typeName(val))
+	}
+	return b, nil
+}
+
+// ---- lexer ----
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ","}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], pos: start})
+		case c == '\'' || c == '"':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(src) && src[i] != c {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= 'a' && src[i] <= 'z' || src[i] >= 'A' && src[i] <= 'Z' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// ---- parser ----
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords.
+func (p *parser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp && tok.kind != tokIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseImplies() (node, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("implies"); ok {
+		right, err := p.parseImplies()
+		if err != nil {
+			return nil, err
+		}
+		return &logicalNode{op: "implies", left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "or", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "and", left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<=", ">=", "<", ">"); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithNode{op: "-", left: &literalNode{value: float64(0)}, right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return &literalNode{value: n}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{value: nil}, nil
+		case "and", "or", "not", "implies":
+			return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+		}
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(tok)
+		}
+		return &identNode{name: tok.text}, nil
+	case tokOp:
+		if tok.text == "(" {
+			inner, err := p.parseImplies()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("missing ')' at position %d", p.peek().pos)
+			}
+			return inner, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+
+	var args []node
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseImplies()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("missing ')' at position %d", p.peek().pos)
+			}
+			break
+		}
+	}
+
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", name.text, fn.arity, len(args))
+	}
+	return &callNode{name: name.text, fn: fn.call, args: args}, nil
+}
+
+// ---- evaluation ----
+
+type node interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) { return n.value, nil }
+
+type identNode struct{ name string }
+
+func (n *identNode) eval(env map[string]interface{}) (interface{}, error) {
+	return normalize(env[n.name]), nil
+}
+
+type notNode struct{ operand node }
+
+func (n *notNode) eval(env map[string]interface{}) (interface{}, error) {
+	b, err := evalBool(n.operand, env)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := evalBool(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit
+	switch {
+	case n.op == "and" && !left:
+		return false, nil
+	case n.op == "or" && left:
+		return true, nil
+	case n.op == "implies" && !left:
+		return true, nil
+	}
+
+	return evalBool(n.right, env)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "==" || n.op == "!=" {
+		if !isScalar(left) || !isScalar(right) {
+			return nil, fmt.Errorf("cannot compare %s %s %s", typeName(left), n.op, typeName(right))
+		}
+		eq := left == right
+		if n.op == "==" {
+			return eq, nil
+		}
+		return !eq, nil
+	}
+	if left == nil || right == nil {
+		// Ordering against a missing value is false, as in SQL, so a rule
+		// on an optional field fails instead of erroring
+		return false, nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return compareOrdered(n.op, l, r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return compareOrdered(n.op, l, r), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %s %s %s", typeName(left), n.op, typeName(right))
+}
+
+func compareOrdered[T float64 | string](op string, l, r T) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+type arithNode struct {
+	op          string
+	left, right node
+}
+
+func (n *arithNode) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "+" {
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", n.op, typeName(left), typeName(right))
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	default:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+}
+
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"len": {arity: 1, call: func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(len([]rune(v))), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, fmt.Errorf("len expects a string, got %s", typeName(args[0]))
+	}},
+}
+
+type callNode struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	args []node
+}
+
+func (n *callNode) eval(env map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		val, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return n.fn(args)
+}
+
+func evalBool(n node, env map[string]interface{}) (bool, error) {
+	val, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean, got %s", typeName(val))
+	}
+	return b, nil
+}
+
+// normalize maps Go values from decoded JSON or the database onto the
+// expression types: null, bool, float64 and string.
+func normalize(val interface{}) interface{} {
+	switch v := val.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		return string(v)
+	}
+	return val
+}
+
+func isScalar(val interface{}) bool {
+	switch val.(type) {
+	case nil, bool, float64, string:
+		return true
+	}
+	return false
+}
+
+func typeName(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+func collectIdents(n node, seen map[string]bool) {
+	switch v := n.(type) {
+	case *identNode:
+		seen[v.name] = true
+	case *notNode:
+		collectIdents(v.operand, seen)
+	case *logicalNode:
+		collectIdents(v.left, seen)
+		collectIdents(v.right, seen)
+	case *compareNode:
+		collectIdents(v.left, seen)
+		collectIdents(v.right, seen)
+	case *arithNode:
+		collectIdents(v.left, seen)
+		collectIdents(v.right, seen)
+	case *callNode:
+		for _, arg := range v.args {
+			collectIdents(arg, seen)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalBool(t *testing.T) {
+	env := map[string]interface{}{
+		"start_date":   "2024-01-01",
+		"end_date":     "2024-02-01",
+		"price":        int64(100),
+		"discount":     float64(20),
+		"status":       "done",
+		"completed_at": nil,
+		"title":        "héllo",
+	}
+
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"end_date >= start_date", true},
+		{"discount <= price", true},
+		{"price - discount == 80", true},
+		{"price * 2 > 150 && discount > 0", true},
+		{"status == 'done' implies completed_at != null", false},
+		{"status == 'open' implies completed_at != null", true},
+		{"not (status == 'done') or price > 50", true},
+		{"!(discount > price)", true},
+		{"len(title) == 5", true},
+		{"missing == null", true},
+		{"missing > 1", false},
+		{"missing == null or missing > 1", true},
+		{"-discount < 0", true},
+		{"price % 30 == 10", true},
+		{"discount % 0.5 == 0", true},
+		{"7.5 % 2 == 1.5", true},
+	}
+
+	for _, c := range cases {
+		prog, err := Compile(c.src)
+		if !assert.NoError(t, err, c.src) {
+			continue
+		}
+		got, err := prog.EvalBool(env)
+		assert.NoError(t, err, c.src)
+		assert.Equal(t, c.want, got, c.src)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		"price >",
+		"(price > 1",
+		"price > 'a",
+		"price $ 1",
+		"exec('rm -rf /')",
+		"len(a, b)",
+	} {
+		_, err := Compile(src)
+		assert.Error(t, err, src)
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	prog, err := Compile("price > name")
+	assert.NoError(t, err)
+	_, err = prog.EvalBool(map[string]interface{}{"price": 1.0, "name": "x"})
+	assert.Error(t, err)
+
+	// Modulo by zero is an error, and fractional divisors are no zero
+	prog, err = Compile("price % divisor == 0")
+	assert.NoError(t, err)
+	for _, divisor := range []float64{0, 0.5} {
+		assert.NotPanics(t, func() { prog.EvalBool(map[string]interface{}{"price": 1.0, "divisor": divisor}) })
+	}
+	_, err = prog.EvalBool(map[string]interface{}{"price": 1.0, "divisor": 0.0})
+	assert.Error(t, err)
+
+	prog, err = Compile("price + 1")
+	assert.NoError(t, err)
+	_, err = prog.EvalBool(map[string]interface{}{"price": 1.0})
+	assert.Error(t, err)
+}
+
+func TestIdentifiers(t *testing.T) {
+	prog, err := Compile("status == 'done' implies completed_at != null and len(note) > 0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"completed_at", "note", "status"}, prog.Identifiers())
+}
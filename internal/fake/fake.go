@@ -0,0 +1,260 @@
+// Package fake generates field values that satisfy the constraints declared
+// in skema.yml, for demo and load-test datasets.
+package fake
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/formats"
+)
+
+// attempts bounds the retries spent on one value, e.g. to find an unused
+// value for a unique field or a pattern match within the length limits.
+const attempts = 100
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// epoch anchors generated dates, so a seed always produces the same data.
+var epoch = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator produces values from a seeded source. The same seed and the
+// same sequence of calls yield the same values.
+type Generator struct {
+	rnd      *rand.Rand
+	patterns map[string]*pattern
+	seen     map[string]map[string]bool
+}
+
+type pattern struct {
+	re     *regexp.Regexp
+	parsed *syntax.Regexp
+}
+
+// New returns a generator seeded with seed.
+func New(seed int64) *Generator {
+	return &Generator{
+		rnd:      rand.New(rand.NewSource(seed)),
+		patterns: map[string]*pattern{},
+		seen:     map[string]map[string]bool{},
+	}
+}
+
+// Intn exposes the generator's source, e.g. to pick foreign keys.
+func (g *Generator) Intn(n int) int {
+	return g.rnd.Intn(n)
+}
+
+// Reserve marks a value of a unique field as taken, typically one that is
+// already stored.
+func (g *Generator) Reserve(entity, field string, val interface{}) {
+	g.taken(entity, field)[fmt.Sprint(val)] = true
+}
+
+func (g *Generator) taken(entity, field string) map[string]bool {
+	key := entity + "." + field
+	if g.seen[key] == nil {
+		g.seen[key] = map[string]bool{}
+	}
+	return g.seen[key]
+}
+
+// Value generates a value for a field of an entity. Unique fields never get
+// a value that was generated or reserved before for the same field.
+func (g *Generator) Value(entity string, field config.FieldConfig) (interface{}, error) {
+	taken := g.taken(entity, field.Name)
+	for i := 0; i < attempts; i++ {
+		val, err := g.generate(field, field.Unique && i >= attempts/2)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if !field.Unique {
+			return val, nil
+		}
+		if key := fmt.Sprint(val); !taken[key] {
+			taken[key] = true
+			return val, nil
+		}
+	}
+	return nil, fmt.Errorf("field %s: could not find an unused value", field.Name)
+}
+
+// generate makes one value. suffix asks plain strings to carry a number,
+// which keeps unique fields going once the word lists run out.
+func (g *Generator) generate(field config.FieldConfig, suffix bool) (interface{}, error) {
+	switch field.Type {
+	case "int":
+		lo, hi := g.bounds(field)
+		return int64(lo + g.rnd.Intn(hi-lo+1)), nil
+	case "float":
+		lo, hi := g.bounds(field)
+		val := math.Round((float64(lo)+g.rnd.Float64()*float64(hi-lo))*100) / 100
+		return math.Min(math.Max(val, float64(lo)), float64(hi)), nil
+	case "bool":
+		return g.rnd.Intn(2) == 1, nil
+	case "string", "text", "password":
+		return g.str(field, suffix)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", field.Type)
+	}
+}
+
+// bounds returns the range of numeric values, defaulting to a span of a
+// thousand (a million for unique fields) next to whichever bound is set.
+func (g *Generator) bounds(field config.FieldConfig) (int, int) {
+	span := 1000
+	if field.Unique {
+		span = 1000000
+	}
+	switch {
+	case field.Min != nil && field.Max != nil:
+		return *field.Min, *field.Max
+	case field.Min != nil:
+		return *field.Min, *field.Min + span
+	case field.Max != nil:
+		return *field.Max - span, *field.Max
+	default:
+		return 0, span
+	}
+}
+
+func (g *Generator) str(field config.FieldConfig, suffix bool) (string, error) {
+	switch {
+	case field.Pattern != "":
+		p, err := g.pattern(field.Pattern)
+		if err != nil {
+			return "", err
+		}
+		return g.retry(field, func() string { return g.fromPattern(p.parsed) }, p.re.MatchString)
+	case field.Format != "":
+		format, ok := formats.Lookup(field.Format)
+		gen, known := formatGenerators[field.Format]
+		if !ok || !known {
+			return "", fmt.Errorf("no generator for format %q", field.Format)
+		}
+		return g.retry(field, func() string { return gen(g) }, format.Check)
+	}
+
+	s := g.words(field)
+	if suffix {
+		s = fmt.Sprintf("%s %d", s, g.rnd.Intn(1000000))
+	}
+	return fitLength(s, field), nil
+}
+
+// retry generates until a value passes check and the length limits.
+func (g *Generator) retry(field config.FieldConfig, gen func() string, check func(string) bool) (string, error) {
+	for i := 0; i < attempts; i++ {
+		s := gen()
+		if check(s) && lengthOK(s, field) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("could not generate a value within the length limits")
+}
+
+func (g *Generator) pattern(expr string) (*pattern, error) {
+	if p, ok := g.patterns[expr]; ok {
+		return p, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	p := &pattern{re: re, parsed: parsed.Simplify()}
+	g.patterns[expr] = p
+	return p, nil
+}
+
+// words picks realistic text based on the field's name and type.
+func (g *Generator) words(field config.FieldConfig) string {
+	name := strings.ToLower(field.Name)
+	switch {
+	case field.Type == "text" || strings.Contains(name, "description") || strings.Contains(name, "bio"):
+		return g.paragraph()
+	case strings.Contains(name, "first"):
+		return g.pick(firstNames)
+	case strings.Contains(name, "last") || strings.Contains(name, "surname"):
+		return g.pick(lastNames)
+	case strings.Contains(name, "user"):
+		return strings.ToLower(g.pick(firstNames)) + fmt.Sprint(g.rnd.Intn(100))
+	case strings.HasSuffix(name, "name"):
+		return g.pick(firstNames) + " " + g.pick(lastNames)
+	case strings.Contains(name, "title") || strings.Contains(name, "subject"):
+		return g.title()
+	case strings.Contains(name, "city"):
+		return g.pick(cities)
+	case strings.Contains(name, "country"):
+		return g.pick(countries)
+	case strings.Contains(name, "colo"):
+		return g.pick(colors)
+	default:
+		n := 1 + g.rnd.Intn(3)
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = g.pick(lorem)
+		}
+		return strings.Join(parts, " ")
+	}
+}
+
+func (g *Generator) title() string {
+	n := 2 + g.rnd.Intn(4)
+	parts := make([]string, n)
+	for i := range parts {
+		w := g.pick(lorem)
+		parts[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(parts, " ")
+}
+
+func (g *Generator) paragraph() string {
+	sentences := make([]string, 1+g.rnd.Intn(3))
+	for i := range sentences {
+		words := make([]string, 6+g.rnd.Intn(7))
+		for j := range words {
+			words[j] = g.pick(lorem)
+		}
+		s := strings.Join(words, " ")
+		sentences[i] = strings.ToUpper(s[:1]) + s[1:] + "."
+	}
+	return strings.Join(sentences, " ")
+}
+
+func (g *Generator) pick(list []string) string {
+	return list[g.rnd.Intn(len(list))]
+}
+
+// fitLength pads or truncates s to the field's length limits.
+func fitLength(s string, field config.FieldConfig) string {
+	if field.MinLength != nil {
+		for utf8.RuneCountInString(s) < *field.MinLength {
+			s += "x"
+		}
+	}
+	if field.MaxLength != nil && utf8.RuneCountInString(s) > *field.MaxLength {
+		s = strings.TrimSpace(string([]rune(s)[:*field.MaxLength]))
+		if field.MinLength != nil {
+			for utf8.RuneCountInString(s) < *field.MinLength {
+				s += "x"
+			}
+		}
+	}
+	return s
+}
+
+func lengthOK(s string, field config.FieldConfig) bool {
+	n := utf8.RuneCountInString(s)
+	return (field.MinLength == nil || n >= *field.MinLength) && (field.MaxLength == nil || n <= *field.MaxLength)
+}
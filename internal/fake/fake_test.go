@@ -0,0 +1,96 @@
+package fake
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/formats"
+	"github.com/stretchr/testify/assert"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestPatterns(t *testing.T) {
+	g := New(1)
+	for _, pattern := range []string{
+		`^[A-Z]{3}-\d{4}$`,
+		`^(draft|active|archived)$`,
+		`^[^,\s]+@corp\.example$`,
+		`^\w+(\.\w+)?$`,
+		`^#[0-9a-f]{6}$`,
+		`(?i)^sku_[a-z]{2,}$`,
+	} {
+		re := regexp.MustCompile(pattern)
+		for i := 0; i < 50; i++ {
+			val, err := g.Value("Item", config.FieldConfig{Name: "code", Type: "string", Pattern: pattern})
+			assert.NoError(t, err)
+			assert.Regexp(t, re, val, pattern)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	g := New(1)
+	for name := range formatGenerators {
+		format, ok := formats.Lookup(name)
+		assert.True(t, ok, name)
+		for i := 0; i < 50; i++ {
+			val, err := g.Value("Item", config.FieldConfig{Name: "value", Type: "string", Format: name})
+			assert.NoError(t, err)
+			assert.True(t, format.Check(val.(string)), "%s: %v", name, val)
+		}
+	}
+
+	_, err := g.Value("Item", config.FieldConfig{Name: "value", Type: "string", Format: "isbn"})
+	assert.Error(t, err)
+}
+
+func TestConstraints(t *testing.T) {
+	g := New(1)
+	for i := 0; i < 200; i++ {
+		n, err := g.Value("Item", config.FieldConfig{Name: "qty", Type: "int", Min: intPtr(5), Max: intPtr(9)})
+		assert.NoError(t, err)
+		assert.True(t, n.(int64) >= 5 && n.(int64) <= 9)
+
+		f, err := g.Value("Item", config.FieldConfig{Name: "price", Type: "float", Min: intPtr(1), Max: intPtr(2)})
+		assert.NoError(t, err)
+		assert.True(t, f.(float64) >= 1 && f.(float64) <= 2)
+
+		s, err := g.Value("Item", config.FieldConfig{Name: "bio", Type: "text", MinLength: intPtr(10), MaxLength: intPtr(40)})
+		assert.NoError(t, err)
+		assert.True(t, len(s.(string)) >= 10 && len(s.(string)) <= 40, s)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	g := New(1)
+	g.Reserve("User", "email", "taken@example.com")
+
+	seen := map[interface{}]bool{}
+	for i := 0; i < 2000; i++ {
+		val, err := g.Value("User", config.FieldConfig{Name: "name", Type: "string", Unique: true})
+		assert.NoError(t, err)
+		assert.False(t, seen[val], val)
+		seen[val] = true
+	}
+
+	// Only five possible values
+	field := config.FieldConfig{Name: "rank", Type: "int", Unique: true, Min: intPtr(1), Max: intPtr(5)}
+	for i := 0; i < 5; i++ {
+		_, err := g.Value("User", field)
+		assert.NoError(t, err)
+	}
+	_, err := g.Value("User", field)
+	assert.Error(t, err)
+}
+
+func TestDeterministic(t *testing.T) {
+	field := config.FieldConfig{Name: "email", Type: "string", Format: "email"}
+	a, b := New(42), New(42)
+	for i := 0; i < 20; i++ {
+		va, _ := a.Value("User", field)
+		vb, _ := b.Value("User", field)
+		assert.Equal(t, va, vb)
+	}
+}
@@ -0,0 +1,65 @@
+package fake
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// formatGenerators produce values for the built-in formats of
+// internal/formats. Custom formats have no generator and are reported.
+var formatGenerators = map[string]func(g *Generator) string{
+	"email": func(g *Generator) string {
+		return fmt.Sprintf("%s.%s%d@%s",
+			strings.ToLower(g.pick(firstNames)), strings.ToLower(g.pick(lastNames)), g.rnd.Intn(1000), g.pick(domains))
+	},
+	"uuid": func(g *Generator) string {
+		b := make([]byte, 16)
+		g.rnd.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	},
+	"url": func(g *Generator) string {
+		return fmt.Sprintf("https://www.%s/%s", g.pick(domains), g.slug())
+	},
+	"uri": func(g *Generator) string {
+		return fmt.Sprintf("https://%s/%s", g.pick(domains), g.slug())
+	},
+	"ipv4": func(g *Generator) string {
+		return fmt.Sprintf("%d.%d.%d.%d", 1+g.rnd.Intn(223), g.rnd.Intn(256), g.rnd.Intn(256), 1+g.rnd.Intn(254))
+	},
+	"ipv6": func(g *Generator) string {
+		groups := make([]string, 8)
+		groups[0] = "2001"
+		groups[1] = "db8"
+		for i := 2; i < len(groups); i++ {
+			groups[i] = fmt.Sprintf("%x", g.rnd.Intn(0x10000))
+		}
+		return strings.Join(groups, ":")
+	},
+	"phone": func(g *Generator) string {
+		return fmt.Sprintf("+1%d%09d", 2+g.rnd.Intn(8), g.rnd.Intn(1000000000))
+	},
+	"date": func(g *Generator) string {
+		return epoch.AddDate(0, 0, g.rnd.Intn(3*365)).Format("2006-01-02")
+	},
+	"date-time": func(g *Generator) string {
+		return epoch.Add(time.Duration(g.rnd.Int63n(int64(3 * 365 * 24 * time.Hour)))).Truncate(time.Second).Format(time.RFC3339)
+	},
+	"slug": func(g *Generator) string {
+		return g.slug()
+	},
+	"hostname": func(g *Generator) string {
+		return g.pick(lorem) + "." + g.pick(domains)
+	},
+}
+
+func (g *Generator) slug() string {
+	n := 1 + g.rnd.Intn(3)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = g.pick(lorem)
+	}
+	return strings.Join(parts, "-")
+}
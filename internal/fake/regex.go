@@ -0,0 +1,96 @@
+package fake
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// maxRepeat bounds unbounded repetitions such as `*` and `+`.
+const maxRepeat = 8
+
+// printable is the ASCII range preferred when a character class allows it.
+const printableLo, printableHi = ' ', '~'
+
+// fromPattern generates a string matching the regular expression.
+func (g *Generator) fromPattern(re *syntax.Regexp) string {
+	var b strings.Builder
+	g.writeRegexp(&b, re)
+	return b.String()
+}
+
+func (g *Generator) writeRegexp(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(g.fromClass(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte(alphanumeric[g.rnd.Intn(len(alphanumeric))])
+	case syntax.OpCapture:
+		g.writeRegexp(b, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.writeRegexp(b, sub)
+		}
+	case syntax.OpAlternate:
+		g.writeRegexp(b, re.Sub[g.rnd.Intn(len(re.Sub))])
+	case syntax.OpStar:
+		g.repeat(b, re.Sub[0], 0, maxRepeat)
+	case syntax.OpPlus:
+		g.repeat(b, re.Sub[0], 1, maxRepeat)
+	case syntax.OpQuest:
+		g.repeat(b, re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		most := re.Max
+		if most < 0 {
+			most = re.Min + maxRepeat
+		}
+		g.repeat(b, re.Sub[0], re.Min, most)
+	}
+	// Anchors, word boundaries and empty matches produce no text
+}
+
+func (g *Generator) repeat(b *strings.Builder, re *syntax.Regexp, least, most int) {
+	n := least + g.rnd.Intn(most-least+1)
+	for i := 0; i < n; i++ {
+		g.writeRegexp(b, re)
+	}
+}
+
+// fromClass picks a rune from a class given as lo/hi pairs. Printable ASCII
+// is preferred, so negated classes like [^,] do not produce control or
+// exotic characters.
+func (g *Generator) fromClass(ranges []rune) rune {
+	var ascii []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < printableLo {
+			lo = printableLo
+		}
+		if hi > printableHi {
+			hi = printableHi
+		}
+		if lo <= hi {
+			ascii = append(ascii, lo, hi)
+		}
+	}
+	if len(ascii) > 0 {
+		ranges = ascii
+	}
+
+	total := 0
+	for i := 0; i < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+	n := g.rnd.Intn(total)
+	for i := 0; i < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			return ranges[i] + rune(n)
+		}
+		n -= size
+	}
+	return ranges[0]
+}
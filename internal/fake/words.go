@@ -0,0 +1,42 @@
+package fake
+
+var firstNames = []string{
+	"Ada", "Alan", "Alice", "Amara", "Ben", "Carlos", "Chen", "Chloe", "Daniel", "Diego",
+	"Elena", "Emma", "Farah", "Felix", "Grace", "Hana", "Hugo", "Ines", "Ivan", "James",
+	"Jin", "Julia", "Kai", "Kofi", "Lara", "Leo", "Lina", "Lucas", "Maya", "Mei",
+	"Nadia", "Noah", "Olivia", "Omar", "Priya", "Rafael", "Sara", "Tariq", "Yuki", "Zoe",
+}
+
+var lastNames = []string{
+	"Adams", "Ahmed", "Baker", "Costa", "Dubois", "Evans", "Fischer", "Garcia", "Hansen", "Ito",
+	"Jensen", "Kim", "Kowalski", "Lopez", "Martin", "Mensah", "Meyer", "Nguyen", "Novak", "Okafor",
+	"Patel", "Perez", "Quinn", "Rossi", "Santos", "Schmidt", "Silva", "Singh", "Smith", "Tanaka",
+	"Taylor", "Walker", "Wang", "Weber", "Wilson", "Yilmaz", "Young", "Zhang", "Ziegler", "Khan",
+}
+
+var cities = []string{
+	"Amsterdam", "Austin", "Berlin", "Bogota", "Cairo", "Cape Town", "Dublin", "Istanbul", "Jakarta", "Lagos",
+	"Lisbon", "London", "Madrid", "Melbourne", "Mumbai", "Nairobi", "Osaka", "Paris", "Seoul", "Toronto",
+}
+
+var countries = []string{
+	"Argentina", "Australia", "Brazil", "Canada", "Egypt", "France", "Germany", "India", "Indonesia", "Ireland",
+	"Japan", "Kenya", "Mexico", "Nigeria", "Portugal", "South Korea", "Spain", "Turkey", "United Kingdom", "United States",
+}
+
+var colors = []string{
+	"amber", "black", "blue", "coral", "cyan", "gold", "green", "grey", "indigo", "lime",
+	"magenta", "navy", "olive", "orange", "pink", "purple", "red", "silver", "teal", "white",
+}
+
+var domains = []string{"example.com", "example.org", "example.net"}
+
+var lorem = []string{
+	"alpha", "amber", "anchor", "apex", "arc", "atlas", "beacon", "birch", "bloom", "bolt",
+	"breeze", "canyon", "cedar", "cinder", "cliff", "cloud", "comet", "coral", "crest", "dawn",
+	"delta", "dune", "echo", "ember", "fable", "fern", "field", "flint", "forge", "frost",
+	"galaxy", "glade", "granite", "harbor", "haven", "horizon", "island", "ivory", "jade", "lagoon",
+	"lantern", "leaf", "lumen", "maple", "meadow", "mesa", "mist", "nova", "oasis", "orbit",
+	"pebble", "pine", "prism", "quartz", "rain", "ridge", "river", "sable", "sage", "shore",
+	"sierra", "spark", "spruce", "stone", "summit", "tide", "timber", "trail", "vale", "willow",
+}
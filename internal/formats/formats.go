@@ -0,0 +1,123 @@
+// Package formats holds the registry of named string formats that fields can
+// declare with `format:` in skema.yml.
+package formats
+
+import (
+	"net/netip"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format is a named string check together with the OpenAPI `format`
+// keyword it is documented as.
+type Format struct {
+	Name    string
+	OpenAPI string
+	Check   func(string) bool
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Format{}
+)
+
+// Register adds or replaces a format. It is meant to be called before the
+// server is built, e.g. from an init function.
+func Register(f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[f.Name] = f
+}
+
+// Lookup returns the format registered under name.
+func Lookup(name string) (Format, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := registry[name]
+	return f, ok
+}
+
+// Names lists the registered formats in alphabetical order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	emailRegex    = regexp.MustCompile(`^[A-Za-z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)+$`)
+	uuidRegex     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	phoneRegex    = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	slugRegex     = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	hostnameRegex = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+)
+
+func init() {
+	Register(Format{Name: "email", OpenAPI: "email", Check: isEmail})
+	Register(Format{Name: "uuid", OpenAPI: "uuid", Check: uuidRegex.MatchString})
+	Register(Format{Name: "url", OpenAPI: "uri", Check: isURL})
+	Register(Format{Name: "uri", OpenAPI: "uri", Check: isURI})
+	Register(Format{Name: "ipv4", OpenAPI: "ipv4", Check: isIPv4})
+	Register(Format{Name: "ipv6", OpenAPI: "ipv6", Check: isIPv6})
+	Register(Format{Name: "phone", OpenAPI: "phone", Check: phoneRegex.MatchString})
+	Register(Format{Name: "date", OpenAPI: "date", Check: isDate})
+	Register(Format{Name: "date-time", OpenAPI: "date-time", Check: isDateTime})
+	Register(Format{Name: "slug", OpenAPI: "slug", Check: slugRegex.MatchString})
+	Register(Format{Name: "hostname", OpenAPI: "hostname", Check: isHostname})
+}
+
+func isEmail(s string) bool {
+	if len(s) > 254 {
+		return false
+	}
+	at := strings.LastIndex(s, "@")
+	return at > 0 && at <= 64 && emailRegex.MatchString(s)
+}
+
+// isURL accepts absolute http(s) URLs with a host.
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isURI accepts any absolute URI, e.g. mailto: or urn: schemes.
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "" || u.Path != "")
+}
+
+func isIPv4(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is4()
+}
+
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6() && addr.Zone() == ""
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func isDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339, s)
+	return err == nil
+}
+
+func isHostname(s string) bool {
+	return len(s) <= 253 && hostnameRegex.MatchString(s)
+}
@@ -0,0 +1,62 @@
+package formats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinFormats(t *testing.T) {
+	cases := []struct {
+		format string
+		value  string
+		valid  bool
+	}{
+		{"email", "alice@example.com", true},
+		{"email", "Alice.Smith@Example.Museum", true},
+		{"email", "alice@localhost", false},
+		{"email", "not-an-email", false},
+		{"uuid", "123e4567-e89b-12d3-a456-426614174000", true},
+		{"uuid", "123e4567e89b12d3a456426614174000", false},
+		{"url", "https://example.com/path?q=1", true},
+		{"url", "ftp://example.com", false},
+		{"url", "example.com", false},
+		{"uri", "mailto:alice@example.com", true},
+		{"uri", "/relative/path", false},
+		{"ipv4", "192.168.0.1", true},
+		{"ipv4", "256.1.1.1", false},
+		{"ipv4", "::1", false},
+		{"ipv6", "2001:db8::1", true},
+		{"ipv6", "10.0.0.1", false},
+		{"phone", "+14155552671", true},
+		{"phone", "4155552671", false},
+		{"date", "2024-02-29", true},
+		{"date", "2023-02-29", false},
+		{"date-time", "2024-02-29T10:00:00Z", true},
+		{"date-time", "2024-02-29 10:00", false},
+		{"slug", "hello-world-2", true},
+		{"slug", "Hello World", false},
+		{"hostname", "api.example.com", true},
+		{"hostname", "-bad.example.com", false},
+	}
+
+	for _, c := range cases {
+		f, ok := Lookup(c.format)
+		assert.True(t, ok, c.format)
+		assert.Equal(t, c.valid, f.Check(c.value), "%s: %q", c.format, c.value)
+	}
+}
+
+func TestRegisterCustomFormat(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(registry, "even-length")
+	})
+	Register(Format{Name: "even-length", Check: func(s string) bool { return len(s)%2 == 0 }})
+
+	f, ok := Lookup("even-length")
+	assert.True(t, ok)
+	assert.True(t, f.Check("ab"))
+	assert.Contains(t, Names(), "even-length")
+}
@@ -0,0 +1,120 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var b64 = base64.RawURLEncoding
+
+// sign builds a token with an asymmetric key the way an identity provider
+// would.
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims Claims) string {
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	p, _ := json.Marshal(claims)
+	signing := b64.EncodeToString(h) + "." + b64.EncodeToString(p)
+	sum := crypto.SHA256.New()
+	sum.Write([]byte(signing))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum.Sum(nil))
+		assert.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, sum.Sum(nil))
+		assert.NoError(t, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signing + "." + b64.EncodeToString(sig)
+}
+
+func TestHMAC(t *testing.T) {
+	now := time.Now()
+	token, err := SignHMAC(Claims{"sub": "42", "exp": now.Add(time.Hour).Unix()}, []byte("secret"))
+	assert.NoError(t, err)
+
+	claims, err := Verify(token, Keys{Secret: []byte("secret")}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", claims["sub"])
+
+	_, err = Verify(token, Keys{Secret: []byte("other")}, now)
+	assert.ErrorIs(t, err, ErrSignature)
+
+	_, err = Verify(token, Keys{Secret: []byte("secret")}, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	// HMAC tokens are not accepted when only public keys are configured
+	_, err = Verify(token, Keys{}, now)
+	assert.Error(t, err)
+
+	parts := strings.Split(token, ".")
+	none := b64.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	_, err = Verify(none, Keys{Secret: []byte("secret")}, now)
+	assert.Error(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "r1", "n": b64.EncodeToString(rsaKey.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "e1", "crv": "P-256", "x": b64.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))), "y": b64.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	public, err := LoadJWKS(path)
+	assert.NoError(t, err)
+	assert.Len(t, public, 2)
+	keys := Keys{Public: public}
+
+	claims := Claims{"sub": "ada", "roles": []string{"admin"}}
+	for _, token := range []string{
+		sign(t, "RS256", "r1", rsaKey, claims),
+		sign(t, "ES256", "e1", ecKey, claims),
+		sign(t, "ES256", "", ecKey, claims),
+	} {
+		got, err := Verify(token, keys, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin"}, got.Strings("roles"))
+	}
+
+	// Key ids must match the signing key
+	_, err = Verify(sign(t, "RS256", "e1", rsaKey, claims), keys, time.Now())
+	assert.ErrorIs(t, err, ErrSignature)
+
+	// ES384 needs a P-384 key, even when a P-256 signature over SHA-384 checks out
+	h, _ := json.Marshal(map[string]string{"alg": "ES384", "kid": "e1"})
+	p, _ := json.Marshal(claims)
+	signing := b64.EncodeToString(h) + "." + b64.EncodeToString(p)
+	digest := crypto.SHA384.New()
+	digest.Write([]byte(signing))
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest.Sum(nil))
+	assert.NoError(t, err)
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	_, err = Verify(signing+"."+b64.EncodeToString(sig), keys, time.Now())
+	assert.ErrorIs(t, err, ErrSignature)
+}
+
+func TestClaimsStrings(t *testing.T) {
+	c := Claims{"scope": "products:read orders:write", "realm_access": map[string]interface{}{"roles": []interface{}{"admin", "staff"}}}
+	assert.Equal(t, []string{"products:read", "orders:write"}, c.Strings("scope"))
+	assert.Equal(t, []string{"admin", "staff"}, c.Strings("realm_access.roles"))
+	assert.Nil(t, c.Strings("missing.path"))
+}
@@ -0,0 +1,389 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/jwt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// tokenIssuer is the iss claim of the tokens issued by POST /auth/login.
+const tokenIssuer = "skema"
+
+// revokedTokensTable holds the ids of revoked tokens until they expire.
+const revokedTokensTable = "auth_revoked_tokens"
+
+// dummyHash is compared against when a username is unknown, so failed
+// logins take as long whether or not the user exists.
+var dummyHash = []byte("$2a$10$xGxdrihCOx5csysT6fMGgeQpfzyb8sLTUSldn9qVhfFiAZeXhBi8S")
+
+var errInvalidLogin = errors.New("invalid credentials")
+
+// userStore signs in the records of the entity named in auth.users and
+// issues the tokens they authenticate with.
+type userStore struct {
+	entity     config.EntityConfig
+	username   string
+	password   string
+	roles      string
+	scopes     []string
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func newUserStore(cfg *config.Config, uc *config.UsersConfig, admins []string) (*userStore, error) {
+	u := &userStore{
+		username:   uc.UsernameField,
+		roles:      uc.RolesField,
+		scopes:     uc.Scopes,
+		secret:     []byte(uc.Secret),
+		accessTTL:  uc.AccessTTL,
+		refreshTTL: uc.RefreshTTL,
+	}
+	if u.username == "" {
+		u.username = "email"
+	}
+	if len(u.scopes) == 0 {
+		u.scopes = []string{"*:read"}
+	}
+	if u.accessTTL == 0 {
+		u.accessTTL = 15 * time.Minute
+	}
+	if u.refreshTTL == 0 {
+		u.refreshTTL = 30 * 24 * time.Hour
+	}
+
+	var ok bool
+	for _, entity := range cfg.Entities {
+		if strings.EqualFold(entity.Name, uc.Entity) {
+			u.entity, ok = entity, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown entity %q", uc.Entity)
+	}
+
+	fields := map[string]bool{}
+	for _, field := range u.entity.Fields {
+		fields[field.Name] = true
+		if field.Type != "password" {
+			continue
+		}
+		if u.password != "" {
+			return nil, fmt.Errorf("entity %s has more than one password field", u.entity.Name)
+		}
+		u.password = field.Name
+	}
+	if u.password == "" {
+		return nil, fmt.Errorf("entity %s needs a field of type password", u.entity.Name)
+	}
+	if !fields[u.username] {
+		return nil, fmt.Errorf("username_field %q is not a field of %s", u.username, u.entity.Name)
+	}
+	if u.roles != "" && !fields[u.roles] {
+		return nil, fmt.Errorf("roles_field %q is not a field of %s", u.roles, u.entity.Name)
+	}
+	if u.roles != "" && !protectedField(u.entity, u.roles, admins) {
+		return nil, fmt.Errorf("roles_field %q must be read_only or hidden, or only writable by admin roles, so users can't grant themselves roles", u.roles)
+	}
+	for _, scope := range u.scopes {
+		if err := checkScope(cfg, scope); err != nil {
+			return nil, fmt.Errorf("scopes: %w", err)
+		}
+	}
+
+	if uc.SecretEnv != "" {
+		u.secret = []byte(os.Getenv(uc.SecretEnv))
+		if len(u.secret) == 0 {
+			return nil, fmt.Errorf("%s is not set", uc.SecretEnv)
+		}
+	}
+	if len(u.secret) == 0 {
+		return nil, errors.New("set secret or secret_env")
+	}
+	return u, nil
+}
+
+// protectedField reports whether only trusted callers and admins can write
+// a field: it is read_only or hidden, or every other role that may create
+// or update records has a write list without it.
+func protectedField(entity config.EntityConfig, name string, admins []string) bool {
+	for _, field := range entity.Fields {
+		if field.Name == name && (field.ReadOnly || field.Hidden) {
+			return true
+		}
+	}
+	if len(entity.Permissions) == 0 {
+		return false
+	}
+	for role, perm := range entity.Permissions {
+		if contains(admins, role) {
+			continue
+		}
+		writes := contains(perm.Operations, "*") || contains(perm.Operations, "create") || contains(perm.Operations, "update")
+		if writes && (perm.Write == nil || contains(perm.Write, name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// rolesOf reads the comma separated roles of a user record.
+func (u *userStore) rolesOf(user map[string]interface{}) []string {
+	roles := []string{}
+	if u.roles == "" || user[u.roles] == nil {
+		return roles
+	}
+	for _, role := range strings.Split(fmt.Sprint(user[u.roles]), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// issue signs a new access and refresh token pair for a user. Both carry
+// the extra claims, such as the user's tenant.
+func (u *userStore) issue(user map[string]interface{}, extra jwt.Claims, now time.Time) (map[string]interface{}, error) {
+	subject := fmt.Sprint(user["id"])
+	accessClaims := jwt.Claims{
+		"iss":   tokenIssuer,
+		"sub":   subject,
+		"typ":   "access",
+		"jti":   newTokenID(),
+		"iat":   now.Unix(),
+		"exp":   now.Add(u.accessTTL).Unix(),
+		"roles": u.rolesOf(user),
+		"scope": strings.Join(u.scopes, " "),
+	}
+	refreshClaims := jwt.Claims{
+		"iss": tokenIssuer,
+		"sub": subject,
+		"typ": "refresh",
+		"jti": newTokenID(),
+		"iat": now.Unix(),
+		"exp": now.Add(u.refreshTTL).Unix(),
+	}
+	for name, val := range extra {
+		accessClaims[name] = val
+		refreshClaims[name] = val
+	}
+
+	access, err := jwt.SignHMAC(accessClaims, u.secret)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := jwt.SignHMAC(refreshClaims, u.secret)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"access_token":  access,
+		"refresh_token": refresh,
+		"token_type":    "Bearer",
+		"expires_in":    int(u.accessTTL.Seconds()),
+	}, nil
+}
+
+// verify checks a token issued by this store, of the given type, that has
+// not been revoked.
+func (u *userStore) verify(db *gorm.DB, token, typ string, now time.Time) (jwt.Claims, error) {
+	claims, err := jwt.Verify(token, jwt.Keys{Secret: u.secret}, now)
+	if err != nil {
+		return nil, err
+	}
+	if claims["iss"] != tokenIssuer || claims["typ"] != typ {
+		return nil, fmt.Errorf("not a %s token", typ)
+	}
+	var count int64
+	if err := db.Table(revokedTokensTable).Where("jti = ?", claims["jti"]).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("token revoked")
+	}
+	return claims, nil
+}
+
+// revoke rejects a token until it expires, and forgets revoked tokens
+// that expired meanwhile.
+func (u *userStore) revoke(db *gorm.DB, claims jwt.Claims, now time.Time) error {
+	expires, _ := claims.Time("exp")
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE expires_at < ?", revokedTokensTable), now.Add(-jwt.Leeway)).Error; err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("INSERT OR IGNORE INTO %s (jti, expires_at) VALUES (?, ?)", revokedTokensTable), claims["jti"], expires).Error
+	})
+}
+
+func newTokenID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// verifyToken accepts access tokens issued by POST /auth/login, then
+// tokens of the configured identity provider.
+func (s *Server) verifyToken(token string, now time.Time) (*Principal, error) {
+	if users := s.auth.users; users != nil {
+		claims, err := users.verify(s.system(), token, "access", now)
+		if err == nil {
+			subject, _ := claims["sub"].(string)
+			return &Principal{
+				Subject: subject,
+				Scopes:  claims.Strings("scope"),
+				Roles:   claims.Strings("roles"),
+				Claims:  claims,
+			}, nil
+		}
+		if s.auth.jwt == nil {
+			return nil, err
+		}
+		p, jwtErr := s.auth.jwt.verify(token, now)
+		if jwtErr != nil {
+			return nil, jwtErr
+		}
+		// Tokens the user store issued but rejected, e.g. refresh or revoked
+		// ones, must not pass as external JWTs either
+		if p.Claims["iss"] == tokenIssuer {
+			return nil, err
+		}
+		return p, nil
+	}
+	return s.auth.jwt.verify(token, now)
+}
+
+// setupAccountRoutes serves login, token refresh and logout for the user
+// store.
+func (s *Server) setupAccountRoutes() {
+	users := s.auth.users
+	s.Router.Route("/auth", func(r chi.Router) {
+		r.Use(s.requireTenant)
+		r.Post("/login", func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid JSON")
+				return
+			}
+			username, _ := body[users.username].(string)
+			password, _ := body[users.password].(string)
+			if username == "" || password == "" {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("%s and %s are required", users.username, users.password))
+				return
+			}
+
+			db := s.trusted(r)
+			user := map[string]interface{}{}
+			res := s.table(db, users.entity).Where(fmt.Sprintf("%s = ?", users.username), username).Limit(1).Scan(&user)
+			if res.Error != nil {
+				writeError(w, http.StatusInternalServerError, res.Error.Error())
+				return
+			}
+			if res.RowsAffected == 0 {
+				bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+				writeError(w, http.StatusUnauthorized, errInvalidLogin.Error())
+				return
+			}
+			if !checkPassword(user[users.password], password) {
+				writeError(w, http.StatusUnauthorized, errInvalidLogin.Error())
+				return
+			}
+			s.writeTokens(w, r, user)
+		})
+
+		r.Post("/refresh", func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				RefreshToken string `json:"refresh_token"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+				writeError(w, http.StatusBadRequest, "refresh_token is required")
+				return
+			}
+
+			claims, err := users.verify(s.system(), body.RefreshToken, "refresh", time.Now())
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, "invalid refresh token: "+err.Error())
+				return
+			}
+			if tenant, _ := claims[s.tenantClaim()].(string); tenant != tenantFrom(r.Context()) {
+				writeError(w, http.StatusUnauthorized, "invalid refresh token: issued for another tenant")
+				return
+			}
+			// Roles are read again, and removed users can no longer refresh
+			user, err := s.findRecord(s.trusted(r), users.entity, claims["sub"])
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, "invalid refresh token: user not found")
+				return
+			}
+			if err := users.revoke(s.system(), claims, time.Now()); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			s.writeTokens(w, r, user)
+		})
+
+		r.Post("/logout", func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				RefreshToken string `json:"refresh_token"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+
+			db := s.system()
+			now := time.Now()
+			var revoke []jwt.Claims
+			if token, ok := bearerToken(r); ok {
+				if claims, err := users.verify(db, token, "access", now); err == nil {
+					revoke = append(revoke, claims)
+				}
+			}
+			if body.RefreshToken != "" {
+				claims, err := users.verify(db, body.RefreshToken, "refresh", now)
+				if err != nil {
+					writeError(w, http.StatusUnauthorized, "invalid refresh token: "+err.Error())
+					return
+				}
+				revoke = append(revoke, claims)
+			}
+			if len(revoke) == 0 {
+				writeError(w, http.StatusBadRequest, "send the access token or a refresh_token to revoke")
+				return
+			}
+			for _, claims := range revoke {
+				if err := users.revoke(db, claims, now); err != nil {
+					writeError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+}
+
+func (s *Server) writeTokens(w http.ResponseWriter, r *http.Request, user map[string]interface{}) {
+	extra := jwt.Claims{}
+	if tenant := tenantFrom(r.Context()); tenant != "" {
+		extra[s.tenantClaim()] = tenant
+	}
+	tokens, err := s.auth.users.issue(user, extra, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tokens,
+	})
+}
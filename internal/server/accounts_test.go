@@ -0,0 +1,256 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserAccounts(t *testing.T) {
+	minLength := 8
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "User",
+				Fields: []config.FieldConfig{
+					{Name: "email", Type: "string", Unique: true, Required: true},
+					{Name: "password", Type: "password", Required: true, MinLength: &minLength},
+					{Name: "role", Type: "string", ReadOnly: true},
+				},
+			},
+			{
+				Name:       "Task",
+				OwnerField: "user_id",
+				Fields: []config.FieldConfig{
+					{Name: "title", Type: "string"},
+					{Name: "user_id", Type: "int"},
+				},
+			},
+		},
+		Auth: &config.AuthConfig{
+			AnonymousScopes: []string{"users:write"},
+			Users:           &config.UsersConfig{Entity: "User", RolesField: "role", Scopes: []string{"*:*"}, Secret: "test-secret"},
+		},
+	}
+
+	os.Remove("test_accounts.db")
+	database, err := db.InitDB(cfg, "test_accounts.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_accounts.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(email, password string) (int, map[string]interface{}) {
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		w := do("POST", "/auth/login", "", map[string]interface{}{"email": email, "password": password})
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data
+	}
+
+	// Sign up: the password is validated, stored hashed and never returned
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/users", "", map[string]interface{}{"email": "ann@example.com", "password": "short"}).Code)
+	w := do("POST", "/users", "", map[string]interface{}{"email": "ann@example.com", "password": "correct horse"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	var stored string
+	database.Table("users").Select("password").Where("id = 1").Scan(&stored)
+	assert.True(t, isPasswordHash(stored))
+	assert.True(t, checkPassword(stored, "correct horse"))
+
+	// A client-made hash is hashed like any other password
+	hash, _ := bcrypt.GenerateFromPassword([]byte("x"), bcrypt.MinCost)
+	assert.Equal(t, http.StatusCreated, do("POST", "/users", "", map[string]interface{}{"email": "mal@example.com", "password": string(hash)}).Code)
+	database.Table("users").Select("password").Where("email = ?", "mal@example.com").Scan(&stored)
+	assert.NotEqual(t, string(hash), stored)
+	assert.True(t, checkPassword(stored, string(hash)))
+	code, _ := login("mal@example.com", "x")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Seeds and CLI imports keep existing hashes
+	imported, err := srv.createRecord(srv.system(), cfg.Entities[0], map[string]interface{}{"email": "imp@example.com", "password": string(hash)}, "")
+	assert.NoError(t, err)
+	database.Table("users").Select("password").Where("id = ?", imported["id"]).Scan(&stored)
+	assert.Equal(t, string(hash), stored)
+	code, _ = login("imp@example.com", "x")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Users can't grant themselves roles
+	assert.Equal(t, http.StatusCreated, do("POST", "/users", "", map[string]interface{}{"email": "eve@example.com", "password": "correct horse", "role": "admin"}).Code)
+	var role *string
+	database.Table("users").Select("role").Where("email = ?", "eve@example.com").Scan(&role)
+	assert.Nil(t, role)
+
+	// Login
+	code, _ = login("ann@example.com", "wrong password")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = login("bob@example.com", "correct horse")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, tokens := login("ann@example.com", "correct horse")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Bearer", tokens["token_type"])
+	access := tokens["access_token"].(string)
+	refresh := tokens["refresh_token"].(string)
+
+	// Access tokens authenticate the user, whose id owns their records
+	w = do("POST", "/tasks", access, map[string]interface{}{"title": "Water plants"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"user_id":1`)
+	w = do("GET", "/users/1", access, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "password")
+	assert.NotContains(t, do("GET", "/users?format=csv", access, nil).Body.String(), "password")
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/tasks", refresh, nil).Code)
+
+	// Refresh tokens are single use
+	w = do("POST", "/auth/refresh", "", map[string]interface{}{"refresh_token": refresh})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var refreshed struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/auth/refresh", "", map[string]interface{}{"refresh_token": refresh}).Code)
+
+	// Logout revokes the presented tokens
+	access = refreshed.Data["access_token"].(string)
+	w = do("POST", "/auth/logout", access, map[string]interface{}{"refresh_token": refreshed.Data["refresh_token"]})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/tasks", access, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/auth/refresh", "", map[string]interface{}{"refresh_token": refreshed.Data["refresh_token"]}).Code)
+
+	// Seeding the same plaintext password twice leaves the user unchanged
+	seeds := []config.SeedConfig{{Entity: "User", Records: []map[string]interface{}{{"email": "root@example.com", "password": "seeded secret", "role": "admin"}}}}
+	_, err = srv.Seed(seeds)
+	assert.NoError(t, err)
+	report, err := srv.Seed(seeds)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Unchanged["User"])
+	code, tokens = login("root@example.com", "seeded secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, http.StatusOK, do("GET", "/tasks", tokens["access_token"].(string), nil).Code)
+}
+
+func TestUserTokensWithJWT(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "User", Fields: []config.FieldConfig{
+				{Name: "email", Type: "string", Unique: true},
+				{Name: "password", Type: "password"},
+			}},
+		},
+		Auth: &config.AuthConfig{
+			JWT:   &config.JWTConfig{Secret: "idp-secret", DefaultScopes: []string{"*:*"}},
+			Users: &config.UsersConfig{Entity: "User", Secret: "user-secret"},
+		},
+	}
+
+	os.Remove("test_accounts_jwt.db")
+	database, err := db.InitDB(cfg, "test_accounts_jwt.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_accounts_jwt.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+	// Let the jwt section accept everything the user store signs
+	srv.auth.jwt.keys.Secret = srv.auth.users.secret
+
+	_, err = srv.Seed([]config.SeedConfig{{Entity: "User", Records: []map[string]interface{}{{"email": "ann@example.com", "password": "correct horse"}}}})
+	assert.NoError(t, err)
+
+	do := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	w := do("POST", "/auth/login", "", map[string]interface{}{"email": "ann@example.com", "password": "correct horse"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	access, refresh := resp.Data["access_token"].(string), resp.Data["refresh_token"].(string)
+
+	// Tokens the user store rejects are not retried as external JWTs
+	assert.Equal(t, http.StatusOK, do("GET", "/users", access, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/users", refresh, nil).Code)
+	assert.Equal(t, http.StatusNoContent, do("POST", "/auth/logout", access, map[string]interface{}{}).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/users", access, nil).Code)
+}
+
+func TestInvalidUsersConfig(t *testing.T) {
+	entities := []config.EntityConfig{{Name: "User", Fields: []config.FieldConfig{
+		{Name: "email", Type: "string"},
+		{Name: "password", Type: "password"},
+	}}}
+	for _, users := range []*config.UsersConfig{
+		{Entity: "Account", Secret: "s"},
+		{Entity: "User"},
+		{Entity: "User", Secret: "s", UsernameField: "login"},
+		{Entity: "User", Secret: "s", RolesField: "role"},
+		{Entity: "User", Secret: "s", Scopes: []string{"posts:read"}},
+	} {
+		cfg := &config.Config{Entities: entities, Auth: &config.AuthConfig{Users: users}}
+		_, err := NewServer(cfg, nil)
+		assert.Error(t, err)
+	}
+
+	// The user store and the jwt section must not share a secret
+	_, err := NewServer(&config.Config{Entities: entities, Auth: &config.AuthConfig{
+		JWT:   &config.JWTConfig{Secret: "s"},
+		Users: &config.UsersConfig{Entity: "User", Secret: "s"},
+	}}, nil)
+	assert.ErrorContains(t, err, "secret")
+
+	cfg := &config.Config{
+		Entities: []config.EntityConfig{{Name: "User", Fields: []config.FieldConfig{{Name: "email", Type: "string"}}}},
+		Auth:     &config.AuthConfig{Users: &config.UsersConfig{Entity: "User", Secret: "s"}},
+	}
+	_, err = NewServer(cfg, nil)
+	assert.Error(t, err)
+
+	// The roles field must be protected from the users themselves
+	withRole := append(entities[0].Fields, config.FieldConfig{Name: "role", Type: "string"})
+	cfg = &config.Config{
+		Entities: []config.EntityConfig{{Name: "User", Fields: withRole}},
+		Auth:     &config.AuthConfig{Users: &config.UsersConfig{Entity: "User", Secret: "s", RolesField: "role"}},
+	}
+	_, err = NewServer(cfg, nil)
+	assert.ErrorContains(t, err, "roles_field")
+
+	cfg.Entities[0].Permissions = map[string]config.PermissionConfig{
+		"admin":    {Operations: []string{"*"}},
+		"customer": {Operations: []string{"get", "update"}, Write: []string{"email", "password"}},
+	}
+	srv, err := NewServer(cfg, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*:read"}, srv.auth.users.scopes)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iamajraj/skema/internal/config"
+)
+
+var aggregateFuncs = []string{"count", "sum", "avg", "min", "max"}
+
+// handleAggregate serves GET /{entities}/_aggregate, e.g.
+// ?group_by=status&count=*&sum=total_amount&avg=price. Filters work as on
+// the list endpoint.
+func (s *Server) handleAggregate(entity config.EntityConfig) http.HandlerFunc {
+	fields := make(map[string]config.FieldConfig, len(entity.Fields))
+	for _, field := range entity.Fields {
+		fields[field.Name] = field
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		var selects, groups, used []string
+		var errs []ValidationError
+
+		for _, name := range splitList(params.Get("group_by")) {
+			if _, ok := fields[name]; !ok {
+				errs = append(errs, ValidationError{Field: name, Rule: "group_by", Message: fmt.Sprintf("cannot group by unknown field '%s'", name)})
+				continue
+			}
+			groups = append(groups, name)
+			selects = append(selects, name)
+			used = append(used, name)
+		}
+
+		for _, fn := range aggregateFuncs {
+			for _, name := range splitList(params.Get(fn)) {
+				if fn == "count" && name == "*" {
+					selects = append(selects, "COUNT(*) AS count")
+					continue
+				}
+
+				field, ok := fields[name]
+				if !ok {
+					errs = append(errs, ValidationError{Field: name, Rule: fn, Message: fmt.Sprintf("cannot aggregate unknown field '%s'", name)})
+					continue
+				}
+				if fn != "count" && field.Type != "int" && field.Type != "float" {
+					errs = append(errs, ValidationError{Field: name, Rule: fn, Message: fmt.Sprintf("%s needs a numeric field, '%s' is %s", fn, name, field.Type)})
+					continue
+				}
+				selects = append(selects, fmt.Sprintf("%s(%s) AS %s_%s", strings.ToUpper(fn), name, fn, name))
+				used = append(used, name)
+			}
+		}
+
+		if len(errs) > 0 {
+			writeError(w, http.StatusBadRequest, "invalid aggregation", errs...)
+			return
+		}
+		db := s.db(r)
+		if denied := s.unreadable(db, entity, append(used, filterFields(entity, r)...)); len(denied) > 0 {
+			writeError(w, http.StatusForbidden, errForbidden.Error(), denied...)
+			return
+		}
+		if len(selects) == len(groups) {
+			selects = append(selects, "COUNT(*) AS count")
+		}
+
+		query := s.filteredQuery(db, entity, r).Select(strings.Join(selects, ", "))
+		if len(groups) > 0 {
+			query = query.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+		}
+
+		results := []map[string]interface{}{}
+		if err := query.Find(&results).Error; err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    results,
+		})
+	}
+}
+
+// splitList splits a comma separated query parameter, dropping blanks.
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "Order",
+				Fields: []config.FieldConfig{
+					{Name: "status", Type: "string"},
+					{Name: "region", Type: "string"},
+					{Name: "total_amount", Type: "float"},
+				},
+			},
+		},
+	}
+
+	os.Remove("test_aggregate.db")
+	database, err := db.InitDB(cfg, "test_aggregate.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_aggregate.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	for _, o := range []map[string]interface{}{
+		{"status": "paid", "region": "eu", "total_amount": 10},
+		{"status": "paid", "region": "us", "total_amount": 30},
+		{"status": "open", "region": "eu", "total_amount": 5},
+	} {
+		body, _ := json.Marshal(o)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/orders", bytes.NewBuffer(body)))
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	get := func(path string) (*httptest.ResponseRecorder, []map[string]interface{}) {
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var resp struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	w, rows := get("/orders/_aggregate?group_by=status&count=*&sum=total_amount&avg=total_amount")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []map[string]interface{}{
+		{"status": "open", "count": float64(1), "sum_total_amount": float64(5), "avg_total_amount": float64(5)},
+		{"status": "paid", "count": float64(2), "sum_total_amount": float64(40), "avg_total_amount": float64(20)},
+	}, rows)
+
+	// List filters apply
+	_, rows = get("/orders/_aggregate?region=eu&max=total_amount")
+	assert.Equal(t, []map[string]interface{}{{"max_total_amount": float64(10)}}, rows)
+
+	// Only numeric fields can be summed
+	w, _ = get("/orders/_aggregate?sum=status")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Principal is the authenticated caller of a request. Claims holds the
+// verified JWT claims and is nil for API keys.
+type Principal struct {
+	Subject string
+	Scopes  []string
+	Roles   []string
+	Claims  map[string]interface{}
+	Tenant  string // set for API keys bound to one tenant
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the caller stored by the authenticate
+// middleware, or nil for anonymous requests. Handlers mounted on the
+// server's router can use it to read the caller's roles and claims.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// authenticator holds the credentials accepted by the server. API keys are
+// only kept as hashes.
+type authenticator struct {
+	header    string
+	keys      map[string]*Principal // by hash
+	anonymous []string
+	admins    []string
+	jwt       *tokenVerifier
+	users     *userStore
+}
+
+// HashAPIKey returns the form in which an API key is stored and configured.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func newAuthenticator(cfg *config.Config) (*authenticator, error) {
+	if cfg.Auth == nil {
+		return nil, nil
+	}
+
+	a := &authenticator{
+		header:    cfg.Auth.Header,
+		keys:      map[string]*Principal{},
+		anonymous: cfg.Auth.AnonymousScopes,
+		admins:    cfg.Auth.AdminRoles,
+	}
+	if a.header == "" {
+		a.header = "X-API-Key"
+	}
+	if len(a.admins) == 0 {
+		a.admins = []string{"admin"}
+	}
+	for _, scope := range a.anonymous {
+		if err := checkScope(cfg, scope); err != nil {
+			return nil, fmt.Errorf("auth: anonymous_scopes: %w", err)
+		}
+	}
+
+	keys, err := loadAPIKeys(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("auth: every API key needs a name")
+		}
+		hash := key.Hash
+		switch {
+		case key.Key != "" && hash != "":
+			return nil, fmt.Errorf("auth: API key %s: set either key or hash, not both", key.Name)
+		case key.Key != "":
+			hash = HashAPIKey(key.Key)
+		case !strings.HasPrefix(hash, "sha256:") || len(hash) != len("sha256:")+64:
+			return nil, fmt.Errorf("auth: API key %s: hash must be sha256:<64 hex digits>", key.Name)
+		}
+		hash = strings.ToLower(hash)
+		if _, dup := a.keys[hash]; dup {
+			return nil, fmt.Errorf("auth: API key %s is configured twice", key.Name)
+		}
+		for _, scope := range key.Scopes {
+			if err := checkScope(cfg, scope); err != nil {
+				return nil, fmt.Errorf("auth: API key %s: %w", key.Name, err)
+			}
+		}
+		if key.Tenant != "" {
+			if cfg.Tenancy == nil {
+				return nil, fmt.Errorf("auth: API key %s: tenant needs a tenancy section", key.Name)
+			}
+			if !tenantID.MatchString(key.Tenant) {
+				return nil, fmt.Errorf("auth: API key %s: invalid tenant %q", key.Name, key.Tenant)
+			}
+		}
+		a.keys[hash] = &Principal{Subject: key.Name, Scopes: key.Scopes, Roles: key.Roles, Tenant: key.Tenant}
+	}
+
+	if cfg.Auth.JWT != nil {
+		v, err := newTokenVerifier(cfg, cfg.Auth.JWT)
+		if err != nil {
+			return nil, fmt.Errorf("auth: jwt: %w", err)
+		}
+		a.jwt = v
+	}
+
+	if cfg.Auth.Users != nil {
+		u, err := newUserStore(cfg, cfg.Auth.Users, a.admins)
+		if err != nil {
+			return nil, fmt.Errorf("auth: users: %w", err)
+		}
+		if a.jwt != nil && len(a.jwt.keys.Secret) > 0 && bytes.Equal(a.jwt.keys.Secret, u.secret) {
+			return nil, fmt.Errorf("auth: users: secret must differ from the jwt secret")
+		}
+		a.users = u
+	}
+	return a, nil
+}
+
+// loadAPIKeys collects the keys declared inline, in the env var and in the
+// file of the auth section.
+func loadAPIKeys(auth *config.AuthConfig) ([]config.APIKeyConfig, error) {
+	keys := append([]config.APIKeyConfig{}, auth.APIKeys...)
+
+	if auth.APIKeysEnv != "" {
+		if value := os.Getenv(auth.APIKeysEnv); value != "" {
+			var fromEnv []config.APIKeyConfig
+			if err := yaml.Unmarshal([]byte(value), &fromEnv); err != nil {
+				return nil, fmt.Errorf("auth: parsing %s: %w", auth.APIKeysEnv, err)
+			}
+			keys = append(keys, fromEnv...)
+		}
+	}
+
+	if auth.APIKeysFile != "" {
+		data, err := os.ReadFile(auth.APIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		var fromFile []config.APIKeyConfig
+		if err := yaml.Unmarshal(data, &fromFile); err != nil {
+			return nil, fmt.Errorf("auth: parsing %s: %w", auth.APIKeysFile, err)
+		}
+		keys = append(keys, fromFile...)
+	}
+	return keys, nil
+}
+
+// checkScope rejects scopes that name unknown entities or access levels.
+func checkScope(cfg *config.Config, scope string) error {
+	resource, access, ok := strings.Cut(scope, ":")
+	if !ok || (access != "read" && access != "write" && access != "*") {
+		return fmt.Errorf("invalid scope %q, expected <entities>:read, <entities>:write or <entities>:*", scope)
+	}
+	if resource == "*" {
+		return nil
+	}
+	for _, entity := range cfg.Entities {
+		if strings.EqualFold(resource, tableName(entity.Name)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("scope %q names an unknown entity", scope)
+}
+
+func hasScope(scopes []string, resource, access string) bool {
+	for _, scope := range scopes {
+		res, acc, _ := strings.Cut(scope, ":")
+		if (res == "*" || strings.EqualFold(res, resource)) && (acc == "*" || acc == access) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate resolves the caller's credentials into a Principal. Requests
+// without credentials continue anonymously; invalid credentials are
+// rejected.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var p *Principal
+		if key := r.Header.Get(s.auth.header); key != "" {
+			var ok bool
+			if p, ok = s.auth.keys[HashAPIKey(key)]; !ok {
+				if s.limitFailedAuth(w, r) {
+					writeError(w, http.StatusUnauthorized, "invalid API key")
+				}
+				return
+			}
+		} else if token, ok := bearerToken(r); ok && (s.auth.jwt != nil || s.auth.users != nil) {
+			var err error
+			if p, err = s.verifyToken(token, time.Now()); err != nil {
+				if s.limitFailedAuth(w, r) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					writeError(w, http.StatusUnauthorized, "invalid token: "+err.Error())
+				}
+				return
+			}
+		}
+
+		if p == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	})
+}
+
+// scoped reports whether the caller's scopes reach an entity touched through
+// another one, e.g. by ?expand, facet labels or a nested create. The
+// route's own entity is checked by authorize.
+func (s *Server) scoped(ctx context.Context, entity config.EntityConfig, access string) bool {
+	if s.auth == nil || ctx == nil || ctx.Value(systemKey{}) != nil {
+		return true
+	}
+	scopes := s.auth.anonymous
+	if p := PrincipalFromContext(ctx); p != nil {
+		scopes = p.Scopes
+	}
+	return hasScope(scopes, tableName(entity.Name), access)
+}
+
+// authorizeAny requires some scope, for routes such as /_batch whose
+// operations are authorized one by one later on. It keeps callers that
+// can't reach any entity away from the tenant lookup.
+func (s *Server) authorizeAny(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p := PrincipalFromContext(r.Context())
+		switch {
+		case p != nil && len(p.Scopes) > 0, p == nil && len(s.auth.anonymous) > 0:
+			next.ServeHTTP(w, r)
+		case p == nil:
+			writeError(w, http.StatusUnauthorized, "authentication required")
+		default:
+			writeError(w, http.StatusForbidden, "no scopes")
+		}
+	})
+}
+
+// authorize requires the read or write scope of an entity, depending on
+// the request method.
+func (s *Server) authorize(entity config.EntityConfig) func(http.Handler) http.Handler {
+	resource := tableName(entity.Name)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.auth == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			access := "write"
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				access = "read"
+			}
+
+			p := PrincipalFromContext(r.Context())
+			scopes := s.auth.anonymous
+			if p != nil {
+				scopes = p.Scopes
+			}
+			switch {
+			case hasScope(scopes, resource, access):
+				next.ServeHTTP(w, r)
+			case p == nil:
+				writeError(w, http.StatusUnauthorized, "authentication required")
+			default:
+				writeError(w, http.StatusForbidden, fmt.Sprintf("missing scope %s:%s", resource, access))
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/iamajraj/skema/internal/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	os.Setenv("TEST_SKEMA_KEYS", `[{name: ci, key: ci-secret, scopes: ["*:*"]}]`)
+	defer os.Unsetenv("TEST_SKEMA_KEYS")
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}},
+			{Name: "Order", Fields: []config.FieldConfig{{Name: "total", Type: "float"}}},
+		},
+		Auth: &config.AuthConfig{
+			APIKeys: []config.APIKeyConfig{
+				{Name: "shop", Hash: HashAPIKey("shop-secret"), Scopes: []string{"products:read", "orders:write"}},
+			},
+			APIKeysEnv:      "TEST_SKEMA_KEYS",
+			AnonymousScopes: []string{"products:read"},
+		},
+	}
+
+	os.Remove("test_auth.db")
+	database, err := db.InitDB(cfg, "test_auth.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_auth.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path, key string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("GET", "/products", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/products", "", map[string]interface{}{"name": "Pen"}).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/products", "wrong", nil).Code)
+
+	w := do("POST", "/products", "shop-secret", map[string]interface{}{"name": "Pen"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "missing scope products:write")
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/orders", "shop-secret", map[string]interface{}{"total": 5}).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/orders", "shop-secret", nil).Code)
+
+	// Keys from the environment, and batch operations are checked one by one
+	assert.Equal(t, http.StatusCreated, do("POST", "/products", "ci-secret", map[string]interface{}{"name": "Ink"}).Code)
+	w = do("POST", "/_batch", "shop-secret", map[string]interface{}{"operations": []map[string]interface{}{
+		{"method": "POST", "path": "/orders", "body": map[string]interface{}{"total": 1}},
+		{"method": "DELETE", "path": "/products/1"},
+	}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestScopesOnRelatedEntities(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "Customer", Fields: []config.FieldConfig{{Name: "name", Type: "string"}, {Name: "ssn", Type: "string"}}},
+			{
+				Name:      "Order",
+				Fields:    []config.FieldConfig{{Name: "total", Type: "float"}, {Name: "customer_id", Type: "int"}},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Customer", Field: "customer_id"}},
+			},
+		},
+		Auth: &config.AuthConfig{
+			APIKeys: []config.APIKeyConfig{
+				{Name: "admin", Key: "admin-secret", Scopes: []string{"*:*"}},
+				{Name: "orders", Key: "orders-secret", Scopes: []string{"orders:*"}},
+			},
+		},
+	}
+
+	os.Remove("test_auth_related.db")
+	database, err := db.InitDB(cfg, "test_auth_related.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_auth_related.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path, key string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/customers", "admin-secret", map[string]interface{}{"name": "Ann", "ssn": "123-45-6789"}).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/orders", "orders-secret", map[string]interface{}{"total": 5, "customer_id": 1}).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/customers", "orders-secret", nil).Code)
+
+	// Expanding needs the read scope of the related entity
+	w := do("GET", "/orders?expand=customer", "orders-secret", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "ssn")
+	assert.NotContains(t, w.Body.String(), "Ann")
+	w = do("GET", "/orders/1?expand=customer", "admin-secret", nil)
+	assert.Contains(t, w.Body.String(), "123-45-6789")
+
+	// So do facet labels
+	w = do("GET", "/orders/_facets?fields=customer_id", "orders-secret", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Ann")
+	w = do("GET", "/orders/_facets?fields=customer_id", "admin-secret", nil)
+	assert.Contains(t, w.Body.String(), "Ann")
+
+	// Nested creates need the write scope of every entity they create
+	w = do("POST", "/orders", "orders-secret", map[string]interface{}{"total": 7, "customer": map[string]interface{}{"name": "Mallory"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "missing scope customers:write")
+	var count int64
+	database.Table("customers").Count(&count)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, http.StatusCreated, do("POST", "/orders", "admin-secret", map[string]interface{}{"total": 7, "customer": map[string]interface{}{"name": "Bob"}}).Code)
+}
+
+func TestInvalidAuthConfig(t *testing.T) {
+	entities := []config.EntityConfig{{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}}}
+	for _, auth := range []*config.AuthConfig{
+		{APIKeys: []config.APIKeyConfig{{Name: "a", Key: "k", Scopes: []string{"users:read"}}}},
+		{APIKeys: []config.APIKeyConfig{{Name: "a", Key: "k", Scopes: []string{"products:delete"}}}},
+		{APIKeys: []config.APIKeyConfig{{Name: "a", Hash: "md5:abc"}}},
+		{APIKeys: []config.APIKeyConfig{{Name: "a", Key: "k"}, {Name: "b", Key: "k"}}},
+		{JWT: &config.JWTConfig{Issuer: "https://id.example.com"}},
+		{JWT: &config.JWTConfig{Secret: "s", DefaultScopes: []string{"users:read"}}},
+	} {
+		_, err := NewServer(&config.Config{Entities: entities, Auth: auth}, nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestJWTAuth(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}, History: true},
+		},
+		Auth: &config.AuthConfig{JWT: &config.JWTConfig{
+			Secret:        "jwt-secret",
+			Issuer:        "https://id.example.com",
+			Audience:      "skema",
+			RequiredRoles: []string{"staff"},
+			RolesClaim:    "app.roles",
+			DefaultScopes: []string{"products:read"},
+			RequireExp:    true,
+		}},
+	}
+
+	os.Remove("test_jwt.db")
+	database, err := db.InitDB(cfg, "test_jwt.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_jwt.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	token := func(claims jwt.Claims) string {
+		base := jwt.Claims{
+			"sub": "user-7",
+			"iss": "https://id.example.com",
+			"aud": []string{"skema", "other"},
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"app": map[string]interface{}{"roles": []string{"staff"}},
+		}
+		for k, v := range claims {
+			base[k] = v
+		}
+		signed, err := jwt.SignHMAC(base, []byte("jwt-secret"))
+		assert.NoError(t, err)
+		return signed
+	}
+	do := func(method, path, bearer string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("GET", "/products", token(nil), nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("POST", "/products", token(nil), map[string]interface{}{"name": "Pen"}).Code)
+
+	w := do("POST", "/products", token(jwt.Claims{"scope": "products:write"}), map[string]interface{}{"name": "Pen"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The subject is recorded as the actor
+	w = do("GET", "/products/1/history", token(nil), nil)
+	assert.Contains(t, w.Body.String(), `"actor":"user-7"`)
+
+	for name, bad := range map[string]string{
+		"issuer":   token(jwt.Claims{"iss": "https://evil.example.com"}),
+		"audience": token(jwt.Claims{"aud": "other"}),
+		"role":     token(jwt.Claims{"app": map[string]interface{}{"roles": []string{"guest"}}}),
+		"expired":  token(jwt.Claims{"exp": time.Now().Add(-time.Hour).Unix()}),
+		"no exp":   token(jwt.Claims{"exp": nil}),
+		"garbage":  "not.a.token",
+	} {
+		w := do("GET", "/products", bad, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"), name)
+	}
+
+	// Claims reach handlers mounted on the router
+	srv.Router.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		p := PrincipalFromContext(r.Context())
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sub": p.Subject, "roles": p.Roles, "iss": p.Claims["iss"]})
+	})
+	w = do("GET", "/whoami", token(nil), nil)
+	assert.JSONEq(t, `{"sub":"user-7","roles":["staff"],"iss":"https://id.example.com"}`, w.Body.String())
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// batchOperation is one request inside POST /_batch.
+type batchOperation struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type batchResult struct {
+	Status int         `json:"status"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+var errBatchFailed = errors.New("batch operation failed")
+
+// batchRef matches references to earlier results, e.g. $0.id
+var batchRef = regexp.MustCompile(`\$(\d+)\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// handleBatch runs a list of operations through the router inside a single
+// transaction. The first operation that fails rolls back the whole batch.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeError(w, http.StatusBadRequest, "operations must not be empty")
+		return
+	}
+
+	var results []batchResult
+	failedAt := -1
+
+	err := s.database(r).Transaction(func(tx *gorm.DB) error {
+		ctx := context.WithValue(r.Context(), txKey{}, tx)
+		// Inner requests are routed from scratch, not as part of this route
+		ctx = context.WithValue(ctx, chi.RouteCtxKey, nil)
+
+		for i, op := range req.Operations {
+			result, err := s.runBatchOperation(ctx, r, op, results)
+			if err != nil {
+				result = batchResult{Status: http.StatusBadRequest, Body: map[string]interface{}{
+					"success": false,
+					"error":   map[string]interface{}{"message": err.Error()},
+				}}
+			}
+			results = append(results, result)
+
+			if result.Status >= http.StatusBadRequest {
+				failedAt = i
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBatchFailed) {
+		writeJSON(w, results[failedAt].Status, map[string]interface{}{
+			"success": false,
+			"error": map[string]interface{}{
+				"message": fmt.Sprintf("operation %d failed; no changes were applied", failedAt),
+				"index":   failedAt,
+			},
+			"data": results,
+		})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    results,
+	})
+}
+
+func (s *Server) runBatchOperation(ctx context.Context, parent *http.Request, op batchOperation, previous []batchResult) (batchResult, error) {
+	method := strings.ToUpper(op.Method)
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return batchResult{}, fmt.Errorf("unsupported method %q", op.Method)
+	}
+	if !strings.HasPrefix(op.Path, "/") || strings.HasPrefix(op.Path, "/_batch") {
+		return batchResult{}, fmt.Errorf("invalid path %q", op.Path)
+	}
+
+	path, err := resolveBatchRefs(op.Path, previous)
+	if err != nil {
+		return batchResult{}, err
+	}
+
+	var body []byte
+	if len(op.Body) > 0 {
+		var payload interface{}
+		if err := json.Unmarshal(op.Body, &payload); err != nil {
+			return batchResult{}, fmt.Errorf("invalid body: %v", err)
+		}
+		payload, err = resolveBatchValue(payload, previous)
+		if err != nil {
+			return batchResult{}, err
+		}
+		body, _ = json.Marshal(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return batchResult{}, err
+	}
+	// Inner requests act on behalf of the same caller
+	for key, values := range parent.Header {
+		if key != "Content-Length" {
+			req.Header[key] = values
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = parent.RemoteAddr
+
+	rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+	s.Router.ServeHTTP(rec, req)
+
+	result := batchResult{Status: rec.status}
+	if rec.body.Len() > 0 {
+		var decoded interface{}
+		if err := json.Unmarshal(rec.body.Bytes(), &decoded); err == nil {
+			result.Body = decoded
+		} else {
+			result.Body = rec.body.String()
+		}
+	}
+	return result, nil
+}
+
+// resolveBatchRefs replaces $N.field references in a string.
+func resolveBatchRefs(s string, previous []batchResult) (string, error) {
+	var resolveErr error
+	out := batchRef.ReplaceAllStringFunc(s, func(ref string) string {
+		val, err := lookupBatchRef(ref, previous)
+		if err != nil {
+			resolveErr = err
+			return ref
+		}
+		return fmt.Sprintf("%v", val)
+	})
+	return out, resolveErr
+}
+
+// resolveBatchValue walks a decoded JSON body. A string that is exactly one
+// reference takes the referenced value with its type, so "$0.id" becomes a
+// number; references embedded in longer strings are interpolated.
+func resolveBatchValue(val interface{}, previous []batchResult) (interface{}, error) {
+	switch v := val.(type) {
+	case string:
+		if loc := batchRef.FindStringIndex(v); loc != nil && loc[0] == 0 && loc[1] == len(v) {
+			return lookupBatchRef(v, previous)
+		}
+		return resolveBatchRefs(v, previous)
+	case map[string]interface{}:
+		for key, item := range v {
+			resolved, err := resolveBatchValue(item, previous)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		for i, item := range v {
+			resolved, err := resolveBatchValue(item, previous)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return val, nil
+}
+
+// lookupBatchRef reads a field from the data of an earlier result.
+func lookupBatchRef(ref string, previous []batchResult) (interface{}, error) {
+	m := batchRef.FindStringSubmatch(ref)
+	index, _ := strconv.Atoi(m[1])
+	if index >= len(previous) {
+		return nil, fmt.Errorf("reference %s points to an operation that has not run yet", ref)
+	}
+
+	body, _ := previous[index].Body.(map[string]interface{})
+	data, _ := body["data"].(map[string]interface{})
+	val, ok := data[m[2]]
+	if !ok {
+		return nil, fmt.Errorf("reference %s: operation %d has no field %q", ref, index, m[2])
+	}
+	return val, nil
+}
+
+// responseRecorder captures the response of an inner batch request.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	minQty := 1
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:      "Order",
+				Fields:    []config.FieldConfig{{Name: "customer_name", Type: "string", Required: true}},
+				Relations: []config.RelationConfig{{Type: "has_many", Entity: "OrderItem", Field: "order_id"}},
+			},
+			{
+				Name: "OrderItem",
+				Fields: []config.FieldConfig{
+					{Name: "order_id", Type: "int", Required: true},
+					{Name: "quantity", Type: "int", Min: &minQty},
+				},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Order", Field: "order_id"}},
+			},
+		},
+	}
+
+	os.Remove("test_batch.db")
+	database, err := db.InitDB(cfg, "test_batch.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_batch.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	batch := func(ops ...map[string]interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"operations": ops})
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/_batch", bytes.NewBuffer(body)))
+		return w
+	}
+
+	w := batch(
+		map[string]interface{}{"method": "POST", "path": "/orders", "body": map[string]interface{}{"customer_name": "Ada"}},
+		map[string]interface{}{"method": "POST", "path": "/orderitems", "body": map[string]interface{}{"order_id": "$0.id", "quantity": 2}},
+		map[string]interface{}{"method": "GET", "path": "/orders/$0.id?expand=orderitems"},
+	)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Success bool          `json:"success"`
+		Data    []batchResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Success)
+	assert.Len(t, resp.Data, 3)
+	assert.Equal(t, http.StatusCreated, resp.Data[1].Status)
+	assert.Contains(t, w.Body.String(), `"orderitems":[`)
+
+	// A failing operation rolls back everything before it
+	w = batch(
+		map[string]interface{}{"method": "POST", "path": "/orders", "body": map[string]interface{}{"customer_name": "Grace"}},
+		map[string]interface{}{"method": "POST", "path": "/orderitems", "body": map[string]interface{}{"order_id": "$0.id", "quantity": 0}},
+	)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"index":1`)
+
+	var count int64
+	database.Table("orders").Count(&count)
+	assert.EqualValues(t, 1, count)
+
+	// References must point backwards
+	w = batch(map[string]interface{}{"method": "GET", "path": "/orders/$3.id"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
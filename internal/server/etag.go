@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+)
+
+var errPreconditionFailed = errors.New("precondition failed")
+
+// etag derives a strong entity tag for a record: the version column for
+// versioned entities, otherwise a hash of the id and updated_at.
+func etag(entity config.EntityConfig, record map[string]interface{}) string {
+	if entity.Versioned {
+		return fmt.Sprintf(`"v%v"`, record["version"])
+	}
+
+	updatedAt := record["updated_at"]
+	if t, ok := updatedAt.(time.Time); ok {
+		updatedAt = t.UTC().Format(time.RFC3339Nano)
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%v|%v", record["id"], updatedAt)))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header value
+// lists the given tag. With weak set (If-None-Match), W/"..." tags compare
+// by their opaque value; If-Match only accepts strong matches.
+func etagMatches(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces an If-Match precondition against the current state
+// of a record. An empty header always passes.
+func checkIfMatch(ifMatch string, entity config.EntityConfig, current map[string]interface{}) error {
+	if ifMatch == "" || etagMatches(ifMatch, etag(entity, current), false) {
+		return nil
+	}
+	return errPreconditionFailed
+}
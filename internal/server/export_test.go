@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "Product",
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string"},
+					{Name: "price", Type: "float"},
+					{Name: "note", Type: "text"},
+				},
+			},
+		},
+	}
+
+	os.Remove("test_export.db")
+	database, err := db.InitDB(cfg, "test_export.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_export.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	// More rows than the default page size
+	for i := 0; i < 150; i++ {
+		b, _ := json.Marshal(map[string]interface{}{"name": fmt.Sprintf("item %d", i), "price": i})
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/products", bytes.NewBuffer(b)))
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("GET", "/products?format=csv&sort=id:asc", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 151)
+		assert.Equal(t, []string{"id", "name", "price", "note", "created_at", "updated_at"}, records[0])
+		assert.Equal(t, []string{"1", "item 0", "0", ""}, records[1][:4])
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/products?price=3", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 1)
+		var row map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &row))
+		assert.Equal(t, "item 3", row["name"])
+	})
+
+	t.Run("ExplicitPage", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("GET", "/products?format=ndjson&limit=10&offset=145", nil))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 5)
+	})
+}
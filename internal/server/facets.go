@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+// handleFacets serves GET /{entities}/_facets?fields=category_id,status:
+// the distinct values of each field with their counts under the current
+// filters. belongs_to fields also carry the label of the related record.
+func (s *Server) handleFacets(entity config.EntityConfig) http.HandlerFunc {
+	fields := make(map[string]bool, len(entity.Fields))
+	for _, field := range entity.Fields {
+		fields[field.Name] = true
+	}
+	parents := make(map[string]config.RelationConfig)
+	for _, rel := range entity.Relations {
+		if rel.Type == "belongs_to" {
+			parents[rel.Field] = rel
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := s.db(r)
+		names := splitList(r.URL.Query().Get("fields"))
+		if len(names) == 0 {
+			writeError(w, http.StatusBadRequest, "fields is required, e.g. ?fields=status")
+			return
+		}
+
+		var errs []ValidationError
+		for _, name := range names {
+			if !fields[name] {
+				errs = append(errs, ValidationError{Field: name, Rule: "facet", Message: fmt.Sprintf("cannot facet on unknown field '%s'", name)})
+			}
+		}
+		if len(errs) > 0 {
+			writeError(w, http.StatusBadRequest, "invalid facets", errs...)
+			return
+		}
+		if denied := s.unreadable(db, entity, append(names, filterFields(entity, r)...)); len(denied) > 0 {
+			writeError(w, http.StatusForbidden, errForbidden.Error(), denied...)
+			return
+		}
+
+		facets := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			buckets := []map[string]interface{}{}
+			err := s.filteredQuery(db, entity, r).
+				Select(fmt.Sprintf("%s AS value, COUNT(*) AS count", name)).
+				Group(name).
+				Order("count desc, value asc").
+				Find(&buckets).Error
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			if rel, ok := parents[name]; ok {
+				s.labelBuckets(db, rel, buckets)
+			}
+			facets[name] = buckets
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    facets,
+		})
+	}
+}
+
+// labelBuckets adds the display label of the related record to each facet
+// bucket of a belongs_to field.
+func (s *Server) labelBuckets(db *gorm.DB, rel config.RelationConfig, buckets []map[string]interface{}) {
+	target, ok := s.entityByName(rel.Entity)
+	if !ok {
+		return
+	}
+	g := grantFor(db.Statement.Context, target)
+	if !s.scoped(db.Statement.Context, target, "read") || (!g.allows("get") && !g.allows("list")) {
+		return
+	}
+	label := labelField(target, g)
+	if label == "" {
+		return
+	}
+
+	var ids []interface{}
+	for _, bucket := range buckets {
+		if bucket["value"] != nil {
+			ids = append(ids, bucket["value"])
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	rows := []map[string]interface{}{}
+	if err := s.relatedTable(db, rel.Entity).Select("id, "+label).Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return
+	}
+	labels := make(map[string]interface{}, len(rows))
+	for _, row := range rows {
+		labels[fmt.Sprint(row["id"])] = row[label]
+	}
+	for _, bucket := range buckets {
+		if l, ok := labels[fmt.Sprint(bucket["value"])]; ok {
+			bucket["label"] = l
+		}
+	}
+}
+
+// labelField picks the field that names a record of an entity: "name" or
+// "title" when declared, otherwise the first string field. Only fields the
+// caller may read are considered, so secret fields never become labels.
+func labelField(entity config.EntityConfig, g *grant) string {
+	first := ""
+	for _, field := range entity.Fields {
+		if !g.readable(field) {
+			continue
+		}
+		if field.Name == "name" || field.Name == "title" {
+			return field.Name
+		}
+		if first == "" && field.Type == "string" {
+			first = field.Name
+		}
+	}
+	return first
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFacets(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:   "Category",
+				Fields: []config.FieldConfig{{Name: "name", Type: "string"}},
+			},
+			{
+				Name: "Product",
+				Fields: []config.FieldConfig{
+					{Name: "status", Type: "string"},
+					{Name: "in_stock", Type: "bool"},
+					{Name: "category_id", Type: "int"},
+				},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Category", Field: "category_id"}},
+			},
+		},
+	}
+
+	os.Remove("test_facets.db")
+	database, err := db.InitDB(cfg, "test_facets.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_facets.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	post := func(path string, body map[string]interface{}) {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewBuffer(b)))
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	post("/categorys", map[string]interface{}{"name": "Books"})
+	post("/categorys", map[string]interface{}{"name": "Games"})
+	post("/products", map[string]interface{}{"status": "active", "in_stock": true, "category_id": 1})
+	post("/products", map[string]interface{}{"status": "active", "in_stock": false, "category_id": 1})
+	post("/products", map[string]interface{}{"status": "draft", "in_stock": true, "category_id": 2})
+
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest("GET", "/products/_facets?fields=category_id,status&in_stock=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data map[string][]map[string]interface{} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []map[string]interface{}{
+		{"value": float64(1), "count": float64(1), "label": "Books"},
+		{"value": float64(2), "count": float64(1), "label": "Games"},
+	}, resp.Data["category_id"])
+	assert.Equal(t, []map[string]interface{}{
+		{"value": "active", "count": float64(1)},
+		{"value": "draft", "count": float64(1)},
+	}, resp.Data["status"])
+
+	w = httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest("GET", "/products/_facets?fields=colour", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFacetLabelsSkipSecretFields(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "Supplier",
+				Fields: []config.FieldConfig{
+					{Name: "api_token", Type: "string", Hidden: true},
+					{Name: "contact", Type: "string", WriteOnly: true},
+					{Name: "city", Type: "string"},
+				},
+			},
+			{
+				Name:      "Delivery",
+				Fields:    []config.FieldConfig{{Name: "supplier_id", Type: "int"}},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Supplier", Field: "supplier_id"}},
+			},
+		},
+	}
+
+	os.Remove("test_facet_labels.db")
+	database, err := db.InitDB(cfg, "test_facet_labels.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_facet_labels.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+	_, err = srv.Seed([]config.SeedConfig{
+		{Entity: "Supplier", Records: []map[string]interface{}{{"api_token": "tok_secret", "contact": "ann@example.com", "city": "Lyon"}}},
+		{Entity: "Delivery", Records: []map[string]interface{}{{"supplier_id": 1}}},
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest("GET", "/deliverys/_facets?fields=supplier_id", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"label":"Lyon"`)
+	assert.NotContains(t, w.Body.String(), "tok_secret")
+	assert.NotContains(t, w.Body.String(), "ann@example.com")
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/fake"
+	"gorm.io/gorm"
+)
+
+// fakeAttempts bounds how often a generated record is regenerated when it
+// fails validation, e.g. because of an entity rule.
+const fakeAttempts = 20
+
+// Fake inserts count generated records for each named entity in one
+// transaction and returns how many were created per entity. Entities are
+// filled parents first, following belongs_to relations, and foreign keys
+// point at randomly chosen existing parents. The same seed reproduces the
+// same data on the same database.
+func (s *Server) Fake(entityNames []string, count int, seed int64) (map[string]int, error) {
+	var entities []config.EntityConfig
+	for _, name := range entityNames {
+		entity, ok := s.entityByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown entity %s", name)
+		}
+		entities = append(entities, entity)
+	}
+
+	gen := fake.New(seed)
+	created := map[string]int{}
+	err := s.system().Transaction(func(tx *gorm.DB) error {
+		for _, entity := range parentsFirst(entities) {
+			n, err := s.fakeEntity(tx, gen, entity, count)
+			created[entity.Name] = n
+			if err != nil {
+				return fmt.Errorf("%s: %w", entity.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *Server) fakeEntity(db *gorm.DB, gen *fake.Generator, entity config.EntityConfig, count int) (int, error) {
+	// Ids of the parents each foreign key can point at
+	parents := map[string][]interface{}{}
+	for _, rel := range entity.Relations {
+		if rel.Type != "belongs_to" {
+			continue
+		}
+		var ids []interface{}
+		if err := s.relatedTable(db, rel.Entity).Order("id").Pluck("id", &ids).Error; err != nil {
+			return 0, err
+		}
+		parents[rel.Field] = ids
+	}
+
+	for _, field := range entity.Fields {
+		if _, isKey := parents[field.Name]; isKey || !field.Unique {
+			continue
+		}
+		var existing []interface{}
+		if err := s.tableWithTrashed(db, entity).Pluck(field.Name, &existing).Error; err != nil {
+			return 0, err
+		}
+		for _, val := range existing {
+			gen.Reserve(entity.Name, field.Name, val)
+		}
+	}
+
+	for i := 0; i < count; i++ {
+		var errs []ValidationError
+		var record map[string]interface{}
+		for attempt := 0; attempt < fakeAttempts && record == nil; attempt++ {
+			data := map[string]interface{}{}
+			for _, field := range entity.Fields {
+				if ids, isKey := parents[field.Name]; isKey {
+					if len(ids) > 0 {
+						data[field.Name] = ids[gen.Intn(len(ids))]
+					} else if field.Required {
+						return i, fmt.Errorf("field %s needs existing parent records to point at", field.Name)
+					}
+					continue
+				}
+				val, err := gen.Value(entity.Name, field)
+				if err != nil {
+					return i, err
+				}
+				data[field.Name] = val
+			}
+
+			if errs = s.validateData(db, entity, data); len(errs) > 0 {
+				continue
+			}
+			var err error
+			if record, err = s.createRecord(db, entity, data, "skema fake"); err != nil {
+				return i, err
+			}
+		}
+		if record == nil {
+			return i, fmt.Errorf("could not generate a valid record: %s", errs[0].Message)
+		}
+
+		// Self references can point at records generated in this run
+		for _, rel := range entity.Relations {
+			if rel.Type == "belongs_to" && s.sameEntity(rel.Entity, entity) {
+				parents[rel.Field] = append(parents[rel.Field], record["id"])
+			}
+		}
+	}
+	return count, nil
+}
+
+func (s *Server) sameEntity(name string, entity config.EntityConfig) bool {
+	target, ok := s.entityByName(name)
+	return ok && target.Name == entity.Name
+}
+
+// parentsFirst orders entities so that every belongs_to target in the list
+// comes before the entities pointing at it. Cycles keep the given order.
+func parentsFirst(entities []config.EntityConfig) []config.EntityConfig {
+	index := map[string]int{}
+	for i, entity := range entities {
+		index[tableName(entity.Name)] = i
+	}
+
+	var ordered []config.EntityConfig
+	state := make([]int, len(entities)) // 0 new, 1 visiting, 2 done
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != 0 {
+			return
+		}
+		state[i] = 1
+		for _, rel := range entities[i].Relations {
+			if j, ok := index[tableName(rel.Entity)]; ok && rel.Type == "belongs_to" && j != i {
+				visit(j)
+			}
+		}
+		state[i] = 2
+		ordered = append(ordered, entities[i])
+	}
+	for i := range entities {
+		visit(i)
+	}
+	return ordered
+}
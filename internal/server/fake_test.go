@@ -0,0 +1,69 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "Post",
+				Fields: []config.FieldConfig{
+					{Name: "title", Type: "string", Required: true, MaxLength: intPtr(30)},
+					{Name: "slug", Type: "string", Pattern: `^[a-z]+-\d{3}$`},
+					{Name: "views", Type: "int", Min: intPtr(0), Max: intPtr(10)},
+					{Name: "likes", Type: "int", Min: intPtr(0), Max: intPtr(10)},
+					{Name: "user_id", Type: "int", Required: true},
+				},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "User", Field: "user_id"}},
+				Rules:     []config.RuleConfig{{Expr: "likes <= views", Message: "likes cannot exceed views"}},
+			},
+			{
+				Name: "User",
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string", Required: true},
+					{Name: "email", Type: "string", Format: "email", Unique: true},
+					{Name: "active", Type: "bool"},
+				},
+			},
+		},
+	}
+
+	os.Remove("test_fake.db")
+	database, err := db.InitDB(cfg, "test_fake.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_fake.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	_, err = srv.Fake([]string{"Post"}, 5, 1)
+	assert.ErrorContains(t, err, "user_id")
+
+	// Post is listed first but its parent is filled first
+	created, err := srv.Fake([]string{"Post", "User"}, 50, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"User": 50, "Post": 50}, created)
+
+	var orphans int64
+	database.Table("posts").Where("user_id NOT IN (SELECT id FROM users)").Count(&orphans)
+	assert.Equal(t, int64(0), orphans)
+
+	var broken int64
+	database.Table("posts").Where("likes > views").Count(&broken)
+	assert.Equal(t, int64(0), broken)
+
+	// Unique values stay unique across runs
+	_, err = srv.Fake([]string{"User"}, 50, 1)
+	assert.NoError(t, err)
+	var emails int64
+	database.Table("users").Distinct("email").Count(&emails)
+	assert.Equal(t, int64(100), emails)
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+func historyTableName(entity config.EntityConfig) string {
+	return tableName(entity.Name) + "_history"
+}
+
+// requestActor identifies who made a change, for the history log. The
+// authenticated caller takes precedence over the X-Actor header.
+func requestActor(r *http.Request) string {
+	if p := PrincipalFromContext(r.Context()); p != nil {
+		return p.Subject
+	}
+	return r.Header.Get("X-Actor")
+}
+
+// recordHistory appends a row to the entity's history table when history is
+// enabled. oldValues is nil for creates and newValues is nil for deletes.
+func (s *Server) recordHistory(db *gorm.DB, entity config.EntityConfig, id interface{}, action string, oldValues, newValues map[string]interface{}, actor string) error {
+	if !entity.History {
+		return nil
+	}
+
+	oldJSON, err := encodeValues(oldValues)
+	if err != nil {
+		return err
+	}
+	newJSON, err := encodeValues(newValues)
+	if err != nil {
+		return err
+	}
+
+	row := map[string]interface{}{
+		"record_id":  id,
+		"action":     action,
+		"old_values": oldJSON,
+		"new_values": newJSON,
+		"actor":      actor,
+		"changed_at": time.Now().UTC(),
+	}
+	return db.Table(historyTableName(entity)).Create(&row).Error
+}
+
+// listHistory returns the change log of one record, oldest first.
+func (s *Server) listHistory(db *gorm.DB, entity config.EntityConfig, id interface{}) ([]map[string]interface{}, error) {
+	rows := []map[string]interface{}{}
+	err := db.Table(historyTableName(entity)).
+		Where("record_id = ?", id).
+		Order("changed_at asc, id asc").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		row["old_values"] = decodeValues(row["old_values"])
+		row["new_values"] = decodeValues(row["new_values"])
+	}
+	return rows, nil
+}
+
+// lastValues returns the record as its latest history row left it: the new
+// values, or the old values when the record was deleted. It returns nil
+// when the record has no history.
+func (s *Server) lastValues(db *gorm.DB, entity config.EntityConfig, id interface{}) map[string]interface{} {
+	row := make(map[string]interface{})
+	dbRes := db.Table(historyTableName(entity)).
+		Where("record_id = ?", id).
+		Order("changed_at desc, id desc").
+		Limit(1).
+		Scan(&row)
+	if dbRes.Error != nil || dbRes.RowsAffected == 0 {
+		return nil
+	}
+	values, _ := decodeValues(row["new_values"]).(map[string]interface{})
+	if values == nil {
+		values, _ = decodeValues(row["old_values"]).(map[string]interface{})
+	}
+	return values
+}
+
+// recordAsOf reconstructs a record as it was at the given time from its
+// history. It returns errNotFound if the record did not exist then.
+func (s *Server) recordAsOf(db *gorm.DB, entity config.EntityConfig, id interface{}, asOf time.Time) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	dbRes := db.Table(historyTableName(entity)).
+		Where("record_id = ? AND changed_at <= ?", id, asOf.UTC()).
+		Order("changed_at desc, id desc").
+		Limit(1).
+		Scan(&row)
+	if dbRes.Error != nil {
+		return nil, dbRes.Error
+	}
+	if dbRes.RowsAffected == 0 || row["action"] == "delete" {
+		return nil, errNotFound
+	}
+
+	values, _ := decodeValues(row["new_values"]).(map[string]interface{})
+	if values == nil {
+		return nil, errNotFound
+	}
+	return values, nil
+}
+
+// parseAsOf accepts an RFC 3339 timestamp or a plain date.
+func parseAsOf(val string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", val); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func encodeValues(values map[string]interface{}) (interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func decodeValues(val interface{}) interface{} {
+	var raw []byte
+	switch v := val.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+// ImportOptions control how rows are read and written by Import.
+type ImportOptions struct {
+	// Format is "csv" or "json" (an array of objects).
+	Format string
+	// Columns maps CSV headers or JSON keys to field names. A column mapped
+	// to "-" is ignored.
+	Columns map[string]string
+	// DryRun validates every row and rolls back instead of committing.
+	DryRun bool
+	// Actor is recorded in the change history of imported records.
+	Actor string
+}
+
+// ImportReport summarises an import. Rows are only written when none of
+// them failed and DryRun is off.
+type ImportReport struct {
+	Total    int             `json:"total"`
+	Valid    int             `json:"valid"`
+	Imported int             `json:"imported"`
+	DryRun   bool            `json:"dry_run"`
+	Failed   []ImportFailure `json:"failed"`
+}
+
+// ImportFailure lists the errors of one input row. Line is the line of the
+// input file where the row starts.
+type ImportFailure struct {
+	Line   int               `json:"line"`
+	Errors []ValidationError `json:"errors"`
+}
+
+type importRow struct {
+	line int
+	data map[string]interface{}
+}
+
+var errImportRolledBack = errors.New("import rolled back")
+
+// ParseColumnMap parses a column mapping like "Product Name=name,SKU=-".
+func ParseColumnMap(s string) (map[string]string, error) {
+	columns := map[string]string{}
+	for _, pair := range splitList(s) {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected header=field", pair)
+		}
+		columns[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	return columns, nil
+}
+
+// Import validates rows read from src and creates them as records of the
+// named entity in a single transaction. Each row goes through the same
+// input sanitizing, validation and relation checks as POST /{entities}.
+func (s *Server) Import(entityName string, src io.Reader, opts ImportOptions) (*ImportReport, error) {
+	entity, ok := s.entityByName(entityName)
+	if !ok {
+		return nil, fmt.Errorf("unknown entity %s", entityName)
+	}
+	return s.importRecords(s.system(), entity, src, opts)
+}
+
+func (s *Server) importRecords(db *gorm.DB, entity config.EntityConfig, src io.Reader, opts ImportOptions) (*ImportReport, error) {
+	var rows []importRow
+	var err error
+	switch opts.Format {
+	case "csv":
+		rows, err = readCSVRows(src, opts.Columns)
+	case "json":
+		rows, err = readJSONRows(src, opts.Columns)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", opts.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Total: len(rows), DryRun: opts.DryRun, Failed: []ImportFailure{}}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			s.dropReadOnly(tx, entity, row.data)
+			errs := s.writeDenied(tx, entity, row.data, "create")
+			errs = append(errs, s.stampOwner(tx, entity, row.data, "create")...)
+			errs = append(errs, sanitizeInput(entity, row.data)...)
+			errs = append(errs, s.validateData(tx, entity, row.data)...)
+			if len(errs) > 0 {
+				report.Failed = append(report.Failed, ImportFailure{Line: row.line, Errors: errs})
+				continue
+			}
+
+			// Valid rows are still inserted after a failure, so later rows
+			// can be checked against the ones before them
+			if _, err := s.createRecord(tx, entity, row.data, opts.Actor); err != nil {
+				report.Failed = append(report.Failed, ImportFailure{Line: row.line, Errors: []ValidationError{{
+					Rule:    "insert",
+					Message: err.Error(),
+				}}})
+				continue
+			}
+			report.Valid++
+		}
+
+		if len(report.Failed) > 0 || opts.DryRun {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		return nil, err
+	}
+
+	if err == nil {
+		report.Imported = report.Valid
+	}
+	return report, nil
+}
+
+// readCSVRows reads a CSV file with a header line. Empty cells are left out
+// of the row, so they count as missing rather than as empty strings.
+func readCSVRows(src io.Reader, columns map[string]string) ([]importRow, error) {
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	for i, name := range header {
+		header[i] = mapColumn(strings.TrimSpace(name), columns)
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		data := make(map[string]interface{}, len(header))
+		for i, cell := range record {
+			if i >= len(header) {
+				return nil, fmt.Errorf("line %d: more cells than header columns", line)
+			}
+			if header[i] == "-" || cell == "" {
+				continue
+			}
+			data[header[i]] = cell
+		}
+		rows = append(rows, importRow{line: line, data: data})
+	}
+	return rows, nil
+}
+
+// readJSONRows reads a JSON array of objects, keeping the line on which
+// each object starts.
+func readJSONRows(src io.Reader, columns map[string]string) ([]importRow, error) {
+	body, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("JSON import must be an array of objects")
+	}
+
+	var rows []importRow
+	for dec.More() {
+		line := lineAt(body, int(dec.InputOffset()))
+		var item map[string]interface{}
+		if err := dec.Decode(&item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		data := make(map[string]interface{}, len(item))
+		for key, val := range item {
+			if name := mapColumn(key, columns); name != "-" {
+				data[name] = val
+			}
+		}
+		rows = append(rows, importRow{line: line, data: data})
+	}
+	return rows, nil
+}
+
+func mapColumn(name string, columns map[string]string) string {
+	if mapped, ok := columns[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+// lineAt returns the line of the first value at or after offset, skipping
+// the whitespace and comma that separate array elements.
+func lineAt(body []byte, offset int) int {
+	for offset < len(body) && strings.ContainsRune(" \t\r\n,", rune(body[offset])) {
+		offset++
+	}
+	return bytes.Count(body[:offset], []byte("\n")) + 1
+}
+
+// handleImport serves POST /{entities}/_import. The body is CSV when sent
+// as text/csv, otherwise a JSON array. ?dry_run=true only validates, and
+// ?columns=Header=field,... maps input columns to fields.
+func (s *Server) handleImport(entity config.EntityConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		columns, err := ParseColumnMap(r.URL.Query().Get("columns"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		opts := ImportOptions{
+			Format:  "json",
+			Columns: columns,
+			DryRun:  r.URL.Query().Get("dry_run") == "true",
+			Actor:   requestActor(r),
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") || r.URL.Query().Get("format") == "csv" {
+			opts.Format = "csv"
+		}
+
+		report, err := s.importRecords(s.db(r), entity, r.Body, opts)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if len(report.Failed) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"error": map[string]interface{}{
+					"message": fmt.Sprintf("%d of %d rows failed; nothing was imported", len(report.Failed), report.Total),
+					"rows":    report.Failed,
+				},
+				"data": report,
+			})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    report,
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImport(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:   "Category",
+				Fields: []config.FieldConfig{{Name: "name", Type: "string", Required: true}},
+			},
+			{
+				Name: "Product",
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string", Required: true},
+					{Name: "price", Type: "float", Min: intPtr(0)},
+					{Name: "category_id", Type: "int"},
+				},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Category", Field: "category_id"}},
+			},
+		},
+	}
+
+	os.Remove("test_import.db")
+	database, err := db.InitDB(cfg, "test_import.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_import.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/categorys", bytes.NewBufferString(`{"name":"Books"}`)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	count := func() int {
+		var n int64
+		database.Table("products").Count(&n)
+		return int(n)
+	}
+
+	importCSV := func(query, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/products/_import"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	t.Run("FailedRowsReportLines", func(t *testing.T) {
+		body := "Product Name,price,category_id,SKU\n" +
+			"Go Book,12.5,1,A1\n" +
+			"\"Broken\nTitle\",-3,1,A2\n" +
+			",4,9,A3\n"
+		code, resp := importCSV("?columns=Product%20Name=name,SKU=-", body)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		rows := resp["error"].(map[string]interface{})["rows"].([]interface{})
+		assert.Len(t, rows, 2)
+		first := rows[0].(map[string]interface{})
+		assert.Equal(t, float64(3), first["line"])
+		assert.Equal(t, "min", first["errors"].([]interface{})[0].(map[string]interface{})["rule"])
+		second := rows[1].(map[string]interface{})
+		assert.Equal(t, float64(5), second["line"])
+		assert.Len(t, second["errors"], 2) // name required, category 9 missing
+
+		assert.Equal(t, 0, count())
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		code, resp := importCSV("?dry_run=true", "name,price\nPen,1\nInk,2\n")
+		assert.Equal(t, http.StatusOK, code)
+		data := resp["data"].(map[string]interface{})
+		assert.Equal(t, float64(2), data["valid"])
+		assert.Equal(t, float64(0), data["imported"])
+		assert.Equal(t, 0, count())
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		code, resp := importCSV("", "name,price,category_id\nPen,1,1\nInk,2,\n")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, float64(2), resp["data"].(map[string]interface{})["imported"])
+		assert.Equal(t, 2, count())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		report, err := srv.Import("Product", strings.NewReader("[\n  {\"title\": \"Desk\", \"price\": 80},\n  {\"title\": \"Lamp\", \"price\": \"x\"}\n]"), ImportOptions{
+			Format:  "json",
+			Columns: map[string]string{"title": "name"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Total)
+		assert.Len(t, report.Failed, 1)
+		assert.Equal(t, 3, report.Failed[0].Line)
+		assert.Equal(t, 2, count())
+	})
+}
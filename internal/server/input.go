@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/iamajraj/skema/internal/config"
+)
+
+// systemColumns are managed by Skema and can never be set by clients.
+var systemColumns = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+}
+
+// sanitizeInput drops system columns, strips or rejects keys that are not
+// declared on the entity, and coerces the remaining values to their field
+// types. Values that cannot be coerced are left as-is for validateData to
+// report.
+func sanitizeInput(entity config.EntityConfig, data map[string]interface{}) []ValidationError {
+	fields := make(map[string]config.FieldConfig, len(entity.Fields))
+	for _, field := range entity.Fields {
+		fields[field.Name] = field
+	}
+
+	var errs []ValidationError
+	for key, val := range data {
+		if systemColumns[key] || (entity.Versioned && key == "version") {
+			delete(data, key)
+			continue
+		}
+
+		field, known := fields[key]
+		if !known {
+			if entity.UnknownFields == "strip" {
+				delete(data, key)
+				continue
+			}
+			errs = append(errs, ValidationError{
+				Field:   key,
+				Rule:    "unknown",
+				Message: fmt.Sprintf("field '%s' is not defined on %s", key, entity.Name),
+			})
+			continue
+		}
+
+		data[key] = coerceValue(field.Type, val)
+	}
+
+	return errs
+}
+
+// secret reports whether a field's values stay out of responses, exports,
+// filters and aggregations.
+func secret(field config.FieldConfig) bool {
+	return field.Type == "password" || field.WriteOnly || field.Hidden
+}
+
+// hasSecrets reports whether any field of the entity is secret.
+func hasSecrets(entity config.EntityConfig) bool {
+	for _, field := range entity.Fields {
+		if secret(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// coerceValue converts common client representations (e.g. "42", "true")
+// into the Go type matching the field type.
+func coerceValue(fieldType string, val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+
+	switch fieldType {
+	case "int":
+		switch v := val.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v)
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n
+			}
+		}
+	case "float":
+		if v, ok := val.(string); ok {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return n
+			}
+		}
+	case "bool":
+		switch v := val.(type) {
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b
+			}
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1
+			}
+		}
+	case "string", "text", "password":
+		switch v := val.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	}
+
+	return val
+}
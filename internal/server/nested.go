@@ -0,0 +1,183 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+var errValidation = errors.New("validation failed")
+
+// relationKey is the payload key of a relation, matching the keys used by
+// ?expand: the singular entity name for belongs_to, the plural for has_many.
+func relationKey(rel config.RelationConfig) string {
+	if rel.Type == "has_many" {
+		return strings.ToLower(rel.Entity) + "s"
+	}
+	return strings.ToLower(rel.Entity)
+}
+
+// graphWriter creates a record together with nested belongs_to parents and
+// has_many children. All records are validated; nothing is inserted once
+// any of them has failed, and the caller rolls back the transaction.
+type graphWriter struct {
+	s      *Server
+	db     *gorm.DB
+	actor  string
+	errs   []ValidationError
+	denied []ValidationError
+}
+
+// create validates and inserts data as an entity record. path prefixes
+// validation errors (e.g. "orderitems[0]"). pendingField names a foreign
+// key whose parent could not be created, so its errors are not reported
+// twice.
+func (g *graphWriter) create(entity config.EntityConfig, data map[string]interface{}, path, pendingField string) (map[string]interface{}, error) {
+	type nested struct {
+		rel    config.RelationConfig
+		key    string
+		parent map[string]interface{}
+		items  []map[string]interface{}
+	}
+
+	declared := make(map[string]bool, len(entity.Fields))
+	for _, field := range entity.Fields {
+		declared[field.Name] = true
+	}
+
+	// Pull nested payloads out of the record's own data
+	var parents, children []nested
+	for _, rel := range entity.Relations {
+		key := relationKey(rel)
+		val, ok := data[key]
+		if !ok || declared[key] {
+			continue
+		}
+		switch rel.Type {
+		case "belongs_to":
+			if parent, ok := val.(map[string]interface{}); ok {
+				parents = append(parents, nested{rel: rel, key: key, parent: parent})
+				delete(data, key)
+			}
+		case "has_many":
+			list, ok := val.([]interface{})
+			if !ok {
+				continue
+			}
+			items := make([]map[string]interface{}, 0, len(list))
+			for _, item := range list {
+				child, ok := item.(map[string]interface{})
+				if !ok {
+					items = nil
+					break
+				}
+				items = append(items, child)
+			}
+			if items != nil {
+				children = append(children, nested{rel: rel, key: key, items: items})
+				delete(data, key)
+			}
+		}
+	}
+
+	graph := map[string]interface{}{}
+	pending := map[string]bool{}
+	if pendingField != "" {
+		pending[pendingField] = true
+	}
+
+	// Parents first, so their ids can fill our foreign keys
+	for _, p := range parents {
+		target, ok := g.s.entityByName(p.rel.Entity)
+		if !ok {
+			return nil, fmt.Errorf("relation target %s is not a configured entity", p.rel.Entity)
+		}
+		created, err := g.create(target, p.parent, joinPath(path, p.key), "")
+		if err != nil {
+			return nil, err
+		}
+		if created == nil {
+			pending[p.rel.Field] = true
+			continue
+		}
+		data[p.rel.Field] = created["id"]
+		graph[p.key] = created
+	}
+
+	g.s.dropReadOnly(g.db, entity, data)
+	denied := g.s.writeDenied(g.db, entity, data, "create")
+	if path != "" && !g.s.scoped(g.db.Statement.Context, entity, "write") {
+		denied = append(denied, ValidationError{Rule: "permission", Message: fmt.Sprintf("missing scope %s:write", tableName(entity.Name))})
+	}
+	denied = append(denied, g.s.stampOwner(g.db, entity, data, "create")...)
+	for _, e := range denied {
+		e.Field = joinPath(path, e.Field)
+		g.denied = append(g.denied, e)
+	}
+
+	errs := sanitizeInput(entity, data)
+	errs = append(errs, g.s.validateData(g.db, entity, data)...)
+	for _, e := range errs {
+		if pending[e.Field] {
+			continue
+		}
+		if path != "" {
+			if e.Field == "" {
+				e.Field = path
+			} else {
+				e.Field = path + "." + e.Field
+			}
+		}
+		g.errs = append(g.errs, e)
+	}
+
+	var record map[string]interface{}
+	if len(g.errs) == 0 && len(g.denied) == 0 {
+		var err error
+		record, err = g.s.createRecord(g.db, entity, data, g.actor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Children last, pointing back at the new record
+	for _, c := range children {
+		target, ok := g.s.entityByName(c.rel.Entity)
+		if !ok {
+			return nil, fmt.Errorf("relation target %s is not a configured entity", c.rel.Entity)
+		}
+		created := make([]map[string]interface{}, 0, len(c.items))
+		for i, item := range c.items {
+			childPending := ""
+			if record != nil {
+				item[c.rel.Field] = record["id"]
+			} else {
+				childPending = c.rel.Field
+			}
+			child, err := g.create(target, item, fmt.Sprintf("%s[%d]", joinPath(path, c.key), i), childPending)
+			if err != nil {
+				return nil, err
+			}
+			created = append(created, child)
+		}
+		graph[c.key] = created
+	}
+
+	if record == nil || len(g.errs) > 0 || len(g.denied) > 0 {
+		return nil, nil
+	}
+	for key, val := range graph {
+		record[key] = val
+	}
+	return record, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNestedCreate(t *testing.T) {
+	minQty := 1
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:      "Customer",
+				Fields:    []config.FieldConfig{{Name: "name", Type: "string", Required: true}},
+				Relations: []config.RelationConfig{{Type: "has_many", Entity: "Order", Field: "customer_id"}},
+			},
+			{
+				Name: "Order",
+				Fields: []config.FieldConfig{
+					{Name: "customer_id", Type: "int", Required: true},
+					{Name: "note", Type: "string"},
+				},
+				Relations: []config.RelationConfig{
+					{Type: "belongs_to", Entity: "Customer", Field: "customer_id"},
+					{Type: "has_many", Entity: "OrderItem", Field: "order_id"},
+				},
+			},
+			{
+				Name: "OrderItem",
+				Fields: []config.FieldConfig{
+					{Name: "order_id", Type: "int", Required: true},
+					{Name: "quantity", Type: "int", Min: &minQty},
+				},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Order", Field: "order_id"}},
+			},
+		},
+	}
+
+	os.Remove("test_nested.db")
+	database, err := db.InitDB(cfg, "test_nested.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_nested.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	post := func(body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/orders", bytes.NewBuffer(b)))
+		return w
+	}
+
+	w := post(map[string]interface{}{
+		"note":       "gift",
+		"customer":   map[string]interface{}{"name": "Ada"},
+		"orderitems": []interface{}{map[string]interface{}{"quantity": 2}, map[string]interface{}{"quantity": 1}},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		Data struct {
+			ID         float64                  `json:"id"`
+			CustomerID float64                  `json:"customer_id"`
+			Customer   map[string]interface{}   `json:"customer"`
+			OrderItems []map[string]interface{} `json:"orderitems"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, resp.Data.Customer["id"], resp.Data.CustomerID)
+	assert.Len(t, resp.Data.OrderItems, 2)
+	assert.Equal(t, resp.Data.ID, resp.Data.OrderItems[0]["order_id"])
+
+	// Errors anywhere in the graph roll back the whole request
+	w = post(map[string]interface{}{
+		"customer":   map[string]interface{}{"name": ""},
+		"orderitems": []interface{}{map[string]interface{}{"quantity": 0}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"customer.name"`)
+	assert.Contains(t, w.Body.String(), `"field":"orderitems[0].quantity"`)
+	assert.NotContains(t, w.Body.String(), `"field":"customer_id"`)
+
+	var count int64
+	database.Table("customers").Count(&count)
+	assert.EqualValues(t, 1, count)
+	database.Table("orderitems").Count(&count)
+	assert.EqualValues(t, 2, count)
+}
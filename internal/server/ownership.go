@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+// checkOwnerField rejects owner fields that are not declared fields, or
+// that are used without authentication to tell callers apart.
+func checkOwnerField(cfg *config.Config, entity config.EntityConfig) error {
+	if entity.OwnerField == "" {
+		return nil
+	}
+	if cfg.Auth == nil {
+		return fmt.Errorf("entity %s: owner_field needs an auth section", entity.Name)
+	}
+	if _, ok := ownerFieldConfig(entity); !ok {
+		return fmt.Errorf("entity %s: owner_field %q is not a field", entity.Name, entity.OwnerField)
+	}
+	return nil
+}
+
+func ownerFieldConfig(entity config.EntityConfig) (config.FieldConfig, bool) {
+	for _, field := range entity.Fields {
+		if field.Name == entity.OwnerField {
+			return field, true
+		}
+	}
+	return config.FieldConfig{}, false
+}
+
+// isAdmin reports whether the caller holds one of the configured admin
+// roles.
+func (s *Server) isAdmin(p *Principal) bool {
+	if p == nil || s.auth == nil {
+		return false
+	}
+	for _, role := range p.Roles {
+		if contains(s.auth.admins, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerScope returns the owner field value of the records the caller may
+// see. It returns false when the caller sees every record: the entity has
+// no owner field, or the caller is trusted or an admin.
+func (s *Server) ownerScope(ctx context.Context, entity config.EntityConfig) (interface{}, bool) {
+	if entity.OwnerField == "" || ctx == nil || ctx.Value(systemKey{}) != nil {
+		return nil, false
+	}
+	p := PrincipalFromContext(ctx)
+	if s.isAdmin(p) {
+		return nil, false
+	}
+	if p == nil {
+		// Compares as NULL, so no record matches
+		return nil, true
+	}
+	field, _ := ownerFieldConfig(entity)
+	return coerceValue(field.Type, p.Subject), true
+}
+
+// stampOwner sets the owner field of a record created by the caller, and
+// rejects attempts to hand a record to someone else. Admins may set any
+// owner and default to themselves.
+func (s *Server) stampOwner(db *gorm.DB, entity config.EntityConfig, data map[string]interface{}, op string) []ValidationError {
+	owner, scoped := s.ownerScope(db.Statement.Context, entity)
+	if !scoped {
+		p := PrincipalFromContext(db.Statement.Context)
+		if _, ok := data[entity.OwnerField]; !ok && p != nil && op == "create" && entity.OwnerField != "" {
+			field, _ := ownerFieldConfig(entity)
+			data[entity.OwnerField] = coerceValue(field.Type, p.Subject)
+		}
+		return nil
+	}
+
+	if val, ok := data[entity.OwnerField]; ok && !sameValue(val, owner) {
+		return []ValidationError{{
+			Field:   entity.OwnerField,
+			Rule:    "owner",
+			Message: fmt.Sprintf("field '%s' can only be set to your own id", entity.OwnerField),
+		}}
+	}
+	if op == "create" {
+		data[entity.OwnerField] = owner
+	}
+	return nil
+}
+
+// visible reports whether the caller's tenant and the caller own the
+// record with the given id, including soft-deleted records. It guards
+// lookups that bypass the entity's table, such as the history log, so
+// records that were deleted for good are judged by their last snapshot.
+func (s *Server) visible(db *gorm.DB, entity config.EntityConfig, id interface{}) bool {
+	owner, owned := s.ownerScope(db.Statement.Context, entity)
+	tenant, tenanted := s.tenantScope(db.Statement.Context)
+	if !owned && !tenanted {
+		return true
+	}
+	var count int64
+	s.tableWithTrashed(db, entity).Where("id = ?", id).Count(&count)
+	if count > 0 || !entity.History {
+		return count > 0
+	}
+
+	values := s.lastValues(db, entity, id)
+	if values == nil {
+		return false
+	}
+	if owned && !sameValue(values[entity.OwnerField], owner) {
+		return false
+	}
+	return !tenanted || values[s.Config.TenantColumn()] == tenant
+}
+
+// requireCaller rejects anonymous requests to entities with an owner field.
+func (s *Server) requireCaller(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if PrincipalFromContext(r.Context()) == nil {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
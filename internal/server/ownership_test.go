@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnerField(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:       "Task",
+				OwnerField: "owner",
+				History:    true,
+				Fields: []config.FieldConfig{
+					{Name: "title", Type: "string"},
+					{Name: "done", Type: "bool"},
+					{Name: "owner", Type: "string"},
+				},
+			},
+		},
+		Auth: &config.AuthConfig{
+			APIKeys: []config.APIKeyConfig{
+				{Name: "alice", Key: "alice-secret", Scopes: []string{"*:*"}},
+				{Name: "bob", Key: "bob-secret", Scopes: []string{"*:*"}},
+				{Name: "root", Key: "root-secret", Scopes: []string{"*:*"}, Roles: []string{"admin"}},
+			},
+			AnonymousScopes: []string{"tasks:read"},
+		},
+	}
+
+	os.Remove("test_ownership.db")
+	database, err := db.InitDB(cfg, "test_ownership.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_ownership.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path, key string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+	list := func(path, key string) []map[string]interface{} {
+		var resp struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(do("GET", path, key, nil).Body.Bytes(), &resp)
+		return resp.Data
+	}
+
+	// Creates are stamped with the caller
+	w := do("POST", "/tasks", "alice-secret", map[string]interface{}{"title": "Write docs"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"owner":"alice"`)
+	assert.Equal(t, http.StatusCreated, do("POST", "/tasks", "alice-secret", map[string]interface{}{"title": "Ship", "done": true}).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/tasks", "bob-secret", map[string]interface{}{"title": "Review"}).Code)
+	w = do("POST", "/tasks", "bob-secret", map[string]interface{}{"title": "Sneaky", "owner": "alice"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "can only be set to your own id")
+
+	// Reads and writes only see the caller's records, and compose with filters
+	assert.Len(t, list("/tasks", "alice-secret"), 2)
+	assert.Len(t, list("/tasks?done=true", "alice-secret"), 1)
+	assert.Len(t, list("/tasks", "bob-secret"), 1)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/tasks/1", "bob-secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("PATCH", "/tasks/1", "bob-secret", map[string]interface{}{"done": true}).Code)
+	do("DELETE", "/tasks/1", "bob-secret", nil)
+	assert.Equal(t, http.StatusOK, do("GET", "/tasks/1", "alice-secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/tasks/1/history", "bob-secret", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("PATCH", "/tasks/1", "alice-secret", map[string]interface{}{"owner": "bob"}).Code)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/tasks/1", "alice-secret", map[string]interface{}{"done": true}).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/tasks/1/history", "alice-secret", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/tasks", "", nil).Code)
+
+	// Admins see everything and may assign records
+	assert.Len(t, list("/tasks", "root-secret"), 3)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/tasks/3", "root-secret", map[string]interface{}{"owner": "alice"}).Code)
+	assert.Len(t, list("/tasks", "bob-secret"), 0)
+	assert.Len(t, list("/tasks", "alice-secret"), 3)
+
+	// History outlives a hard delete, for the owner and admins only
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/tasks/1", "alice-secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/tasks/1", "alice-secret", nil).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/tasks/1/history", "alice-secret", nil).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/tasks/1/history", "root-secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/tasks/1/history", "bob-secret", nil).Code)
+}
+
+func TestInvalidOwnerField(t *testing.T) {
+	fields := []config.FieldConfig{{Name: "title", Type: "string"}}
+	for _, cfg := range []*config.Config{
+		{Entities: []config.EntityConfig{{Name: "Task", Fields: fields, OwnerField: "title"}}},
+		{Entities: []config.EntityConfig{{Name: "Task", Fields: fields, OwnerField: "user_id"}}, Auth: &config.AuthConfig{}},
+	} {
+		_, err := NewServer(cfg, nil)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/iamajraj/skema/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordBytes is the longest password bcrypt can hash.
+const maxPasswordBytes = 72
+
+// hashPasswords replaces the plaintext values of password fields in data
+// with bcrypt hashes. With keepHashes, values that already are bcrypt hashes
+// are kept, so trusted callers can import users with their existing hashes.
+// HTTP callers never get that: a client sending a hash it made itself would
+// otherwise bypass min_length and every other password rule.
+func hashPasswords(entity config.EntityConfig, data map[string]interface{}, keepHashes bool) error {
+	for _, field := range entity.Fields {
+		if field.Type != "password" {
+			continue
+		}
+		val, ok := data[field.Name].(string)
+		if !ok || (keepHashes && isPasswordHash(val)) {
+			continue
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(val), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		data[field.Name] = string(hash)
+	}
+	return nil
+}
+
+func isPasswordHash(val string) bool {
+	if len(val) != 60 {
+		return false
+	}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(val, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPassword reports whether password matches a stored hash.
+func checkPassword(hash interface{}, password string) bool {
+	stored, ok := hash.(string)
+	return ok && bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+var operations = []string{"list", "get", "create", "update", "delete"}
+
+var errForbidden = errors.New("permission denied")
+
+type systemKey struct{}
+
+// system returns a database handle for trusted callers such as the CLI
+// commands, which are not subject to entity permissions.
+func (s *Server) system() *gorm.DB {
+	ctx := context.WithValue(context.Background(), systemKey{}, true)
+	if s.tenant != "" {
+		ctx = context.WithValue(ctx, tenantKey{}, s.tenant)
+	}
+	return s.DB.WithContext(ctx)
+}
+
+// isSystem reports whether db was handed out by system or trusted.
+func isSystem(db *gorm.DB) bool {
+	ctx := db.Statement.Context
+	return ctx != nil && ctx.Value(systemKey{}) != nil
+}
+
+// trusted is like system for work the server does on behalf of a request,
+// such as looking up the user logging in. It keeps the request's tenant.
+func (s *Server) trusted(r *http.Request) *gorm.DB {
+	return s.database(r).WithContext(context.WithValue(r.Context(), systemKey{}, true))
+}
+
+// grant is what the caller's roles allow on one entity. A nil field set
+// means every field.
+type grant struct {
+	ops   map[string]bool
+	read  map[string]bool
+	write map[string]bool
+}
+
+// checkPermissions rejects permission blocks naming unknown operations or
+// fields.
+func checkPermissions(entity config.EntityConfig) error {
+	fields := make(map[string]bool, len(entity.Fields))
+	for _, field := range entity.Fields {
+		fields[field.Name] = true
+	}
+	for role, perm := range entity.Permissions {
+		for _, op := range perm.Operations {
+			if op != "*" && !contains(operations, op) {
+				return fmt.Errorf("entity %s: permissions for %s: unknown operation %q, expected one of %s or *", entity.Name, role, op, strings.Join(operations, ", "))
+			}
+		}
+		for _, name := range append(append([]string{}, perm.Read...), perm.Write...) {
+			if !fields[name] {
+				return fmt.Errorf("entity %s: permissions for %s: unknown field %q", entity.Name, role, name)
+			}
+		}
+	}
+	return nil
+}
+
+// grantFor merges the permissions of the caller's roles, plus the * role.
+// It returns nil when the entity has no permissions or the caller is
+// trusted.
+func grantFor(ctx context.Context, entity config.EntityConfig) *grant {
+	if len(entity.Permissions) == 0 || ctx == nil || ctx.Value(systemKey{}) != nil {
+		return nil
+	}
+
+	roles := []string{"*"}
+	if p := PrincipalFromContext(ctx); p != nil {
+		roles = append(roles, p.Roles...)
+	}
+
+	g := &grant{ops: map[string]bool{}, read: map[string]bool{}, write: map[string]bool{}}
+	allRead, allWrite := false, false
+	for _, role := range roles {
+		perm, ok := entity.Permissions[role]
+		if !ok {
+			continue
+		}
+		for _, op := range perm.Operations {
+			g.ops[op] = true
+		}
+		if len(perm.Read) == 0 {
+			allRead = true
+		}
+		for _, name := range perm.Read {
+			g.read[name] = true
+		}
+		if len(perm.Write) == 0 {
+			allWrite = true
+		}
+		for _, name := range perm.Write {
+			g.write[name] = true
+		}
+	}
+	if allRead {
+		g.read = nil
+	}
+	if allWrite {
+		g.write = nil
+	}
+	return g
+}
+
+func (g *grant) allows(op string) bool {
+	return g == nil || g.ops["*"] || g.ops[op]
+}
+
+func (g *grant) canRead(field string) bool {
+	return g == nil || g.read == nil || g.read[field]
+}
+
+func (g *grant) canWrite(field string) bool {
+	return g == nil || g.write == nil || g.write[field]
+}
+
+// readable reports whether a field may appear in responses. Secret fields
+// never do.
+func (g *grant) readable(field config.FieldConfig) bool {
+	return !secret(field) && g.canRead(field.Name)
+}
+
+// dropReadOnly removes the fields clients may not write from data.
+// Trusted callers, such as seeds and command line imports, keep them.
+func (s *Server) dropReadOnly(db *gorm.DB, entity config.EntityConfig, data map[string]interface{}) {
+	if ctx := db.Statement.Context; ctx != nil && ctx.Value(systemKey{}) != nil {
+		return
+	}
+	for _, field := range entity.Fields {
+		if field.ReadOnly || field.Hidden {
+			delete(data, field.Name)
+		}
+	}
+}
+
+// permit rejects requests whose roles may not perform op on the entity.
+func (s *Server) permit(entity config.EntityConfig, op string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if grantFor(r.Context(), entity).allows(op) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if PrincipalFromContext(r.Context()) == nil {
+				writeError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			writeError(w, http.StatusForbidden, fmt.Sprintf("not allowed to %s %s", op, tableName(entity.Name)))
+		})
+	}
+}
+
+// writeDenied reports the operation or the fields of data that the caller
+// may not write.
+func (s *Server) writeDenied(db *gorm.DB, entity config.EntityConfig, data map[string]interface{}, op string) []ValidationError {
+	g := grantFor(db.Statement.Context, entity)
+	if g == nil {
+		return nil
+	}
+	if !g.allows(op) {
+		return []ValidationError{{Rule: "permission", Message: fmt.Sprintf("not allowed to %s %s", op, tableName(entity.Name))}}
+	}
+
+	var errs []ValidationError
+	for _, field := range entity.Fields {
+		if _, ok := data[field.Name]; ok && !g.canWrite(field.Name) {
+			errs = append(errs, ValidationError{
+				Field:   field.Name,
+				Rule:    "permission",
+				Message: fmt.Sprintf("field '%s' is read-only for your role", field.Name),
+			})
+		}
+	}
+	return errs
+}
+
+// unreadable reports the fields the caller may not read, e.g. when they
+// are used for aggregation.
+func (s *Server) unreadable(db *gorm.DB, entity config.EntityConfig, names []string) []ValidationError {
+	g := grantFor(db.Statement.Context, entity)
+	fields := make(map[string]config.FieldConfig, len(entity.Fields))
+	for _, field := range entity.Fields {
+		fields[field.Name] = field
+	}
+
+	var errs []ValidationError
+	for _, name := range names {
+		if field, ok := fields[name]; ok && !g.readable(field) {
+			errs = append(errs, ValidationError{
+				Field:   name,
+				Rule:    "permission",
+				Message: fmt.Sprintf("field '%s' is not readable for your role", name),
+			})
+		}
+	}
+	return errs
+}
+
+// readableColumns lists the columns the caller may read, or nil when every
+// column is readable.
+func (s *Server) readableColumns(db *gorm.DB, entity config.EntityConfig) []string {
+	g := grantFor(db.Statement.Context, entity)
+	if (g == nil || g.read == nil) && !hasSecrets(entity) {
+		return nil
+	}
+	columns := []string{"id"}
+	for _, field := range entity.Fields {
+		if g.readable(field) {
+			columns = append(columns, field.Name)
+		}
+	}
+	columns = append(columns, "created_at", "updated_at")
+	return append(columns, optionalColumns(entity)...)
+}
+
+// redact removes the fields the caller may not read from records, including
+// expanded or nested related records.
+func (s *Server) redact(db *gorm.DB, entity config.EntityConfig, records ...map[string]interface{}) {
+	g := grantFor(db.Statement.Context, entity)
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		for _, field := range entity.Fields {
+			if !g.readable(field) {
+				delete(record, field.Name)
+			}
+		}
+
+		for _, rel := range entity.Relations {
+			key := relationKey(rel)
+			nested, ok := record[key]
+			if !ok {
+				continue
+			}
+			target, ok := s.entityByName(rel.Entity)
+			if !ok {
+				continue
+			}
+			tg := grantFor(db.Statement.Context, target)
+			if !s.scoped(db.Statement.Context, target, "read") || (!tg.allows("get") && !tg.allows("list")) {
+				delete(record, key)
+				continue
+			}
+			switch v := nested.(type) {
+			case map[string]interface{}:
+				s.redact(db, target, v)
+			case []map[string]interface{}:
+				s.redact(db, target, v...)
+			}
+		}
+	}
+}
+
+// optionalColumns are the system columns added by entity options.
+func optionalColumns(entity config.EntityConfig) []string {
+	var columns []string
+	if entity.SoftDelete {
+		columns = append(columns, "deleted_at")
+	}
+	if entity.Versioned {
+		columns = append(columns, "version")
+	}
+	return columns
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissions(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:   "Category",
+				Fields: []config.FieldConfig{{Name: "name", Type: "string"}},
+				Permissions: map[string]config.PermissionConfig{
+					"admin":    {Operations: []string{"*"}},
+					"customer": {Operations: []string{"list", "get"}},
+				},
+			},
+			{
+				Name: "Region",
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string"},
+					{Name: "code", Type: "string"},
+				},
+				Permissions: map[string]config.PermissionConfig{
+					"admin":    {Operations: []string{"*"}},
+					"customer": {Operations: []string{"list", "get"}, Read: []string{"code"}},
+				},
+			},
+			{
+				Name:      "Shipment",
+				Fields:    []config.FieldConfig{{Name: "region_id", Type: "int"}},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Region", Field: "region_id"}},
+			},
+			{
+				Name: "Order",
+				Fields: []config.FieldConfig{
+					{Name: "reference", Type: "string"},
+					{Name: "total_amount", Type: "float"},
+					{Name: "note", Type: "string"},
+				},
+				Permissions: map[string]config.PermissionConfig{
+					"admin": {Operations: []string{"*"}},
+					"customer": {
+						Operations: []string{"list", "get", "create", "update"},
+						Read:       []string{"reference", "total_amount"},
+						Write:      []string{"reference"},
+					},
+				},
+			},
+		},
+		Auth: &config.AuthConfig{
+			APIKeys: []config.APIKeyConfig{
+				{Name: "root", Key: "admin-secret", Scopes: []string{"*:*"}, Roles: []string{"admin"}},
+				{Name: "alice", Key: "customer-secret", Scopes: []string{"*:*"}, Roles: []string{"customer"}},
+				{Name: "bot", Key: "bot-secret", Scopes: []string{"*:*"}},
+			},
+		},
+	}
+
+	os.Remove("test_permissions.db")
+	database, err := db.InitDB(cfg, "test_permissions.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_permissions.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path, key string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Operations
+	assert.Equal(t, http.StatusCreated, do("POST", "/categorys", "admin-secret", map[string]interface{}{"name": "Books"}).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/categorys", "customer-secret", nil).Code)
+	w := do("DELETE", "/categorys/1", "customer-secret", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "not allowed to delete categorys")
+	assert.Equal(t, http.StatusForbidden, do("GET", "/categorys", "bot-secret", nil).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/categorys/1", "admin-secret", nil).Code)
+
+	// Writable fields
+	w = do("POST", "/orders", "customer-secret", map[string]interface{}{"reference": "A1", "total_amount": 10})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "field 'total_amount' is read-only for your role")
+	assert.Equal(t, http.StatusCreated, do("POST", "/orders", "customer-secret", map[string]interface{}{"reference": "A1"}).Code)
+	assert.Equal(t, http.StatusForbidden, do("PATCH", "/orders/1", "customer-secret", map[string]interface{}{"total_amount": 0}).Code)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/orders/1", "admin-secret", map[string]interface{}{"total_amount": 99, "note": "vip"}).Code)
+
+	// Readable fields
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(do("GET", "/orders/1", "customer-secret", nil).Body.Bytes(), &resp)
+	assert.Equal(t, 99.0, resp.Data["total_amount"])
+	assert.NotContains(t, resp.Data, "note")
+
+	w = do("GET", "/orders?format=csv", "customer-secret", nil)
+	assert.NotContains(t, w.Body.String(), "vip")
+	assert.Equal(t, http.StatusForbidden, do("GET", "/orders/_facets?fields=note", "customer-secret", nil).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/orders/_aggregate?sum=total_amount", "customer-secret", nil).Code)
+
+	// Unreadable fields can't be probed with filters or sorting
+	assert.Equal(t, http.StatusForbidden, do("GET", "/orders?note=vip", "customer-secret", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/orders?sort=note:desc", "customer-secret", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/orders/_aggregate?note=vip", "customer-secret", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/orders/_facets?fields=reference&note=vip", "customer-secret", nil).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/orders?sort=total_amount:desc", "customer-secret", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/orders?sort=total_amount%20desc,note", "customer-secret", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/orders?sort=total_amount:sideways", "customer-secret", nil).Code)
+
+	// Facet labels only use what the caller may read of the related record
+	assert.Equal(t, http.StatusCreated, do("POST", "/regions", "admin-secret", map[string]interface{}{"name": "Northern Secret", "code": "N"}).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/shipments", "admin-secret", map[string]interface{}{"region_id": 1}).Code)
+	w = do("GET", "/shipments/_facets?fields=region_id", "customer-secret", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"label":"N"`)
+	assert.NotContains(t, w.Body.String(), "Northern Secret")
+	w = do("GET", "/shipments/_facets?fields=region_id", "bot-secret", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "label")
+
+	// Batches check each operation with the caller's roles
+	w = do("POST", "/_batch", "customer-secret", map[string]interface{}{"operations": []map[string]interface{}{
+		{"method": "POST", "path": "/orders", "body": map[string]interface{}{"reference": "B2", "note": "x"}},
+	}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Seeds and other trusted callers are not restricted
+	_, err = srv.Seed([]config.SeedConfig{{Entity: "Order", Records: []map[string]interface{}{{"reference": "S1", "note": "seeded"}}}})
+	assert.NoError(t, err)
+}
+
+func TestInvalidPermissions(t *testing.T) {
+	for _, perm := range []config.PermissionConfig{
+		{Operations: []string{"destroy"}},
+		{Operations: []string{"get"}, Read: []string{"missing"}},
+		{Operations: []string{"update"}, Write: []string{"missing"}},
+	} {
+		cfg := &config.Config{
+			Entities: []config.EntityConfig{{
+				Name:        "Product",
+				Fields:      []config.FieldConfig{{Name: "name", Type: "string"}},
+				Permissions: map[string]config.PermissionConfig{"customer": perm},
+			}},
+		}
+		_, err := NewServer(cfg, nil)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,431 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+// rateLimitTable holds the saved buckets when rate_limit.persist is set.
+const rateLimitTable = "rate_limit_buckets"
+
+// rateLimitSaveInterval is how often a running server saves its buckets.
+const rateLimitSaveInterval = 5 * time.Second
+
+// rateBudget is the size of a bucket and how fast it refills.
+type rateBudget struct {
+	requests int
+	per      time.Duration
+	burst    int
+}
+
+func newRateBudget(requests int, per time.Duration, burst int) (rateBudget, error) {
+	b := rateBudget{requests: requests, per: per, burst: burst}
+	if b.per == 0 {
+		b.per = time.Minute
+	}
+	if b.burst == 0 {
+		b.burst = b.requests
+	}
+	if b.requests <= 0 {
+		return b, fmt.Errorf("requests must be positive")
+	}
+	if b.per < time.Second {
+		return b, fmt.Errorf("per must be at least 1s")
+	}
+	if b.burst < 0 {
+		return b, fmt.Errorf("burst must not be negative")
+	}
+	return b, nil
+}
+
+// rate is the number of tokens the bucket gains per second.
+func (b rateBudget) rate() float64 {
+	return float64(b.requests) / b.per.Seconds()
+}
+
+// rateRule applies a budget to the requests of one entity and some methods.
+type rateRule struct {
+	name    string          // e.g. rule:/products:POST, names its buckets
+	path    string          // e.g. /products, "" for every route
+	methods map[string]bool // nil for every method
+	budget  rateBudget
+}
+
+// identity names the rule by what it matches rather than its position, so
+// saved buckets still belong to the right rule after rules are added or
+// reordered.
+func (rule rateRule) identity() string {
+	path := rule.path
+	if path == "" {
+		path = "*"
+	}
+	methods := []string{"*"}
+	if rule.methods != nil {
+		methods = methods[:0]
+		for method := range rule.methods {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+	}
+	return "rule:" + path + ":" + strings.Join(methods, ",")
+}
+
+func (rule rateRule) matches(r *http.Request) bool {
+	if rule.path != "" && r.URL.Path != rule.path && !strings.HasPrefix(r.URL.Path, rule.path+"/") {
+		return false
+	}
+	return rule.methods == nil || rule.methods[r.Method]
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	budget  *rateBudget
+}
+
+// refill adds the tokens earned since the last update.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.budget.rate()
+		b.updated = now
+	}
+	b.tokens = math.Min(b.tokens, float64(b.budget.burst))
+}
+
+// rateLimiter keeps a token bucket per client and budget. Buckets are
+// forgotten once they are full again, since a new bucket starts full.
+type rateLimiter struct {
+	by       string
+	ipHeader string
+	hops     int         // entries of ipHeader added by trusted proxies
+	fallback *rateBudget // nil leaves requests without a rule unlimited
+	rules    []rateRule
+	persist  bool
+	now      func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func newRateLimiter(cfg *config.Config) (*rateLimiter, error) {
+	rc := cfg.RateLimit
+	if rc == nil {
+		return nil, nil
+	}
+	l := &rateLimiter{
+		by:       rc.By,
+		ipHeader: rc.IPHeader,
+		hops:     rc.TrustedHops,
+		persist:  rc.Persist,
+		now:      time.Now,
+		buckets:  map[string]*bucket{},
+	}
+
+	switch l.by {
+	case "":
+		l.by = "ip"
+	case "ip":
+	case "api_key", "user":
+		if cfg.Auth == nil {
+			return nil, fmt.Errorf("rate_limit: by %s needs an auth section", l.by)
+		}
+	default:
+		return nil, fmt.Errorf("rate_limit: unknown by %q, expected ip, api_key or user", l.by)
+	}
+
+	switch {
+	case l.hops < 0:
+		return nil, fmt.Errorf("rate_limit: trusted_hops must not be negative")
+	case l.hops > 0 && l.ipHeader == "":
+		return nil, fmt.Errorf("rate_limit: trusted_hops needs ip_header")
+	case l.hops == 0:
+		l.hops = 1
+	}
+
+	if rc.Requests != 0 || rc.Burst != 0 {
+		budget, err := newRateBudget(rc.Requests, rc.Per, rc.Burst)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit: %w", err)
+		}
+		l.fallback = &budget
+	}
+
+	for i, rr := range rc.Rules {
+		budget, err := newRateBudget(rr.Requests, rr.Per, rr.Burst)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit: rule %d: %w", i+1, err)
+		}
+		rule := rateRule{budget: budget}
+		if rr.Entity != "" {
+			var ok bool
+			for _, entity := range cfg.Entities {
+				if strings.EqualFold(entity.Name, rr.Entity) {
+					rule.path, ok = "/"+tableName(entity.Name), true
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("rate_limit: rule %d: unknown entity %q", i+1, rr.Entity)
+			}
+		}
+		for _, method := range rr.Methods {
+			method = strings.ToUpper(method)
+			switch method {
+			case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+			default:
+				return nil, fmt.Errorf("rate_limit: rule %d: unknown method %q", i+1, method)
+			}
+			if rule.methods == nil {
+				rule.methods = map[string]bool{}
+			}
+			rule.methods[method] = true
+		}
+		rule.name = rule.identity()
+		l.rules = append(l.rules, rule)
+	}
+
+	if l.fallback == nil && len(l.rules) == 0 {
+		return nil, fmt.Errorf("rate_limit: set requests or rules")
+	}
+	return l, nil
+}
+
+// match returns the budget of a request and the name its buckets are
+// stored under, or nil when the request is not limited.
+func (l *rateLimiter) match(r *http.Request) (string, *rateBudget) {
+	for i := range l.rules {
+		if l.rules[i].matches(r) {
+			return l.rules[i].name, &l.rules[i].budget
+		}
+	}
+	return "default", l.fallback
+}
+
+// budgetNamed returns the budget stored under a name returned by match.
+func (l *rateLimiter) budgetNamed(name string) *rateBudget {
+	if name == "default" {
+		return l.fallback
+	}
+	for i := range l.rules {
+		if l.rules[i].name == name {
+			return &l.rules[i].budget
+		}
+	}
+	return nil
+}
+
+// rateStatus is the outcome of taking a token, for the response headers.
+type rateStatus struct {
+	allowed   bool
+	remaining int
+	reset     time.Duration // until the bucket is full again
+	retry     time.Duration // until the next token, when not allowed
+}
+
+// take spends a token of the bucket stored under key.
+func (l *rateLimiter) take(key string, budget *rateBudget, now time.Time) rateStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) > time.Minute {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(budget.burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.budget = budget
+	b.refill(now)
+
+	var st rateStatus
+	if b.tokens >= 1 {
+		b.tokens--
+		st.allowed = true
+	} else {
+		st.retry = seconds((1 - b.tokens) / budget.rate())
+	}
+	st.remaining = int(b.tokens)
+	st.reset = seconds((float64(budget.burst) - b.tokens) / budget.rate())
+	return st
+}
+
+// sweep forgets full buckets. The caller holds l.mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.refill(now); b.tokens >= float64(b.budget.burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// ceilSeconds rounds up to whole seconds for the response headers.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// load restores the buckets saved by save. Buckets of rules that are no
+// longer configured are dropped.
+func (l *rateLimiter) load(db *gorm.DB) error {
+	var rows []struct {
+		Client    string
+		Tokens    float64
+		UpdatedAt time.Time
+	}
+	if err := db.Table(rateLimitTable).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, row := range rows {
+		name, _, _ := strings.Cut(row.Client, "|")
+		budget := l.budgetNamed(name)
+		if budget == nil {
+			continue
+		}
+		l.buckets[row.Client] = &bucket{tokens: row.Tokens, updated: row.UpdatedAt, budget: budget}
+	}
+	return nil
+}
+
+// save replaces the saved buckets with the ones that are not full.
+func (l *rateLimiter) save(db *gorm.DB) error {
+	l.mu.Lock()
+	l.sweep(l.now())
+	rows := make([]map[string]interface{}, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		rows = append(rows, map[string]interface{}{"client": key, "tokens": b.tokens, "updated_at": b.updated})
+	}
+	l.mu.Unlock()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + rateLimitTable).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Table(rateLimitTable).CreateInBatches(rows, 500).Error
+	})
+}
+
+// saveEvery saves the buckets until ctx is done.
+func (l *rateLimiter) saveEvery(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.save(db); err != nil {
+				log.Printf("rate_limit: saving buckets: %v", err)
+			}
+		}
+	}
+}
+
+// rateClient names the client a request is counted against. Requests
+// without the credentials the limiter keys on are counted by IP.
+func (s *Server) rateClient(r *http.Request) string {
+	l := s.limiter
+	p := PrincipalFromContext(r.Context())
+	switch {
+	case l.by == "api_key" && p != nil && r.Header.Get(s.auth.header) != "":
+		return "key:" + p.Subject
+	case l.by == "user" && p != nil:
+		if tenant := tenantFrom(r.Context()); tenant != "" {
+			return "user:" + tenant + "/" + p.Subject
+		}
+		return "user:" + p.Subject
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP returns the address the request came from. Behind proxies it
+// reads ip_header from the right: proxies append the address they saw, so
+// the entries further left were sent by the client and can't be trusted.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	if l.ipHeader != "" {
+		var entries []string
+		for _, value := range r.Header.Values(l.ipHeader) {
+			for _, entry := range strings.Split(value, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) > 0 {
+			return entries[max(len(entries)-l.hops, 0)]
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// rateLimit answers 429 once a client has spent its budget. Responses of
+// limited routes carry RateLimit-* headers describing the client's bucket.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.limiter == nil || s.limit(w, r, s.rateClient(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// limitFailedAuth counts a request with invalid credentials against its IP.
+// Such requests are rejected before rateLimit runs, so without this keys and
+// tokens could be guessed at any rate. It reports whether the request may
+// get its 401; otherwise it has already answered 429.
+func (s *Server) limitFailedAuth(w http.ResponseWriter, r *http.Request) bool {
+	return s.limiter == nil || s.limit(w, r, "ip:"+s.limiter.clientIP(r))
+}
+
+// limit spends a token of the client's bucket for the budget matching r and
+// sets the RateLimit-* headers. Once the budget is spent it answers 429 and
+// returns false.
+func (s *Server) limit(w http.ResponseWriter, r *http.Request, client string) bool {
+	l := s.limiter
+	name, budget := l.match(r)
+	if budget == nil {
+		return true
+	}
+
+	st := l.take(name+"|"+client, budget, l.now())
+	policy := fmt.Sprintf("%d;w=%d", budget.requests, int(budget.per.Seconds()))
+	if budget.burst != budget.requests {
+		policy += fmt.Sprintf(";burst=%d", budget.burst)
+	}
+	h := w.Header()
+	h.Set("RateLimit-Policy", policy)
+	h.Set("RateLimit-Limit", strconv.Itoa(budget.burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(st.remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(st.reset)))
+	if !st.allowed {
+		retry := ceilSeconds(st.retry)
+		h.Set("Retry-After", strconv.Itoa(retry))
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry in %d seconds", retry))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}},
+		},
+		RateLimit: &config.RateLimitConfig{
+			Requests: 2,
+			Per:      time.Minute,
+			Persist:  true,
+			Rules: []config.RateLimitRule{
+				{Entity: "Product", Methods: []string{"post"}, Requests: 1, Per: time.Minute},
+			},
+		},
+	}
+
+	os.Remove("test_ratelimit.db")
+	database, err := db.InitDB(cfg, "test_ratelimit.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_ratelimit.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+	now := time.Now()
+	srv.limiter.now = func() time.Time { return now }
+
+	do := func(srv *Server, method, path, ip string) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(map[string]interface{}{"name": "Widget"})
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The default budget allows a burst of two, then one request every 30s
+	w := do(srv, "GET", "/products", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, http.StatusOK, do(srv, "GET", "/products", "10.0.0.1").Code)
+
+	w = do(srv, "GET", "/products", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+
+	// Other clients have their own bucket
+	assert.Equal(t, http.StatusOK, do(srv, "GET", "/products", "10.0.0.2").Code)
+
+	// Rules have a separate, smaller budget
+	assert.Equal(t, http.StatusCreated, do(srv, "POST", "/products", "10.0.0.1").Code)
+	w = do(srv, "POST", "/products", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Buckets refill over time
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, do(srv, "GET", "/products", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(srv, "GET", "/products", "10.0.0.1").Code)
+
+	// Saved buckets survive a restart
+	assert.NoError(t, srv.limiter.save(database))
+	restarted, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+	restarted.limiter.now = func() time.Time { return now }
+	assert.Equal(t, http.StatusTooManyRequests, do(restarted, "GET", "/products", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(restarted, "POST", "/products", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, do(restarted, "GET", "/products", "10.0.0.2").Code)
+
+	// Saved rule buckets follow their rule when rules are added or reordered
+	assert.NoError(t, srv.limiter.save(database))
+	cfg.RateLimit.Rules = append([]config.RateLimitRule{
+		{Methods: []string{"DELETE"}, Requests: 5, Per: time.Minute},
+	}, cfg.RateLimit.Rules...)
+	reordered, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+	reordered.limiter.now = func() time.Time { return now }
+	assert.Equal(t, http.StatusTooManyRequests, do(reordered, "POST", "/products", "10.0.0.1").Code)
+	w = do(reordered, "DELETE", "/products/1", "10.0.0.1")
+	assert.Equal(t, "4", w.Header().Get("RateLimit-Remaining"))
+
+	// Full buckets are forgotten
+	now = now.Add(time.Hour)
+	assert.NoError(t, restarted.limiter.save(database))
+	var count int64
+	database.Table(rateLimitTable).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}},
+		},
+		Auth: &config.AuthConfig{
+			APIKeys: []config.APIKeyConfig{
+				{Name: "script", Key: "script-secret", Scopes: []string{"*:*"}},
+				{Name: "app", Key: "app-secret", Scopes: []string{"*:*"}},
+			},
+			AnonymousScopes: []string{"products:read"},
+		},
+		RateLimit: &config.RateLimitConfig{By: "api_key", Requests: 1},
+	}
+
+	os.Remove("test_ratelimit_keys.db")
+	database, err := db.InitDB(cfg, "test_ratelimit_keys.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_ratelimit_keys.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	doFrom := func(key, ip string) int {
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+	do := func(key string) int { return doFrom(key, "10.0.0.1") }
+
+	// Clients behind one IP are told apart by their key
+	assert.Equal(t, http.StatusOK, do("script-secret"))
+	assert.Equal(t, http.StatusTooManyRequests, do("script-secret"))
+	assert.Equal(t, http.StatusOK, do("app-secret"))
+	assert.Equal(t, http.StatusOK, do(""))
+	assert.Equal(t, http.StatusTooManyRequests, do(""))
+
+	// Invalid keys are counted against the IP, so they can't be guessed freely
+	assert.Equal(t, http.StatusUnauthorized, doFrom("guess-1", "10.0.0.2"))
+	assert.Equal(t, http.StatusTooManyRequests, doFrom("guess-2", "10.0.0.2"))
+}
+
+func TestRateLimitBehindProxy(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}},
+		},
+		RateLimit: &config.RateLimitConfig{Requests: 1, IPHeader: "X-Forwarded-For"},
+	}
+
+	os.Remove("test_ratelimit_proxy.db")
+	database, err := db.InitDB(cfg, "test_ratelimit_proxy.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_ratelimit_proxy.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(srv *Server, forwarded string) int {
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The proxy appends the address it saw; whatever the client sent before it is ignored
+	assert.Equal(t, http.StatusOK, do(srv, "1.1.1.1, 10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do(srv, "2.2.2.2, 10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do(srv, "10.0.0.1"))
+	assert.Equal(t, http.StatusOK, do(srv, "10.0.0.1, 10.0.0.2"))
+
+	// Behind two proxies the client is the second entry from the right
+	cfg.RateLimit.TrustedHops = 2
+	srv, err = NewServer(cfg, database)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do(srv, "1.1.1.1, 10.0.0.3, 172.16.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do(srv, "2.2.2.2, 10.0.0.3, 172.16.0.1"))
+}
+
+func TestInvalidRateLimit(t *testing.T) {
+	entities := []config.EntityConfig{{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}}}
+	for _, rl := range []*config.RateLimitConfig{
+		{},
+		{Requests: -1},
+		{Requests: 10, Per: time.Millisecond},
+		{By: "session", Requests: 10},
+		{Requests: 10, IPHeader: "X-Forwarded-For", TrustedHops: -1},
+		{Requests: 10, TrustedHops: 2},
+		{By: "user", Requests: 10},
+		{Rules: []config.RateLimitRule{{Entity: "Order", Requests: 1}}},
+		{Rules: []config.RateLimitRule{{Methods: []string{"FETCH"}, Requests: 1}}},
+		{Rules: []config.RateLimitRule{{Entity: "Product"}}},
+	} {
+		_, err := NewServer(&config.Config{Entities: entities, RateLimit: rl}, nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestRateLimitSavedOnShutdown(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{Name: "Product", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}},
+		},
+		RateLimit: &config.RateLimitConfig{Requests: 10, Persist: true},
+	}
+
+	os.Remove("test_ratelimit_shutdown.db")
+	database, err := db.InitDB(cfg, "test_ratelimit_shutdown.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_ratelimit_shutdown.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.serve(ctx, ln) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/products")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Stopping long before the next periodic save still keeps the bucket
+	cancel()
+	assert.NoError(t, <-done)
+	var count int64
+	database.Table(rateLimitTable).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
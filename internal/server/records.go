@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+var errNotFound = errors.New("not found")
+
+// findRecord loads a live record by id.
+func (s *Server) findRecord(db *gorm.DB, entity config.EntityConfig, id interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	dbRes := s.table(db, entity).Where("id = ?", id).Scan(&result)
+	if dbRes.Error != nil {
+		return nil, dbRes.Error
+	}
+	if dbRes.RowsAffected == 0 {
+		return nil, errNotFound
+	}
+	return result, nil
+}
+
+// createRecord inserts already validated data and returns the stored row.
+func (s *Server) createRecord(db *gorm.DB, entity config.EntityConfig, data map[string]interface{}, actor string) (map[string]interface{}, error) {
+	now := time.Now()
+	data["created_at"] = now
+	data["updated_at"] = now
+	if err := hashPasswords(entity, data, isSystem(db)); err != nil {
+		return nil, err
+	}
+	if tenant, scoped := s.tenantScope(db.Statement.Context); scoped {
+		data[s.Config.TenantColumn()] = tenant
+	}
+
+	if err := db.Table(tableName(entity.Name)).Create(&data).Error; err != nil {
+		return nil, err
+	}
+
+	// GORM reports the generated primary key as "@id" for map inserts
+	id := data["@id"]
+	delete(data, "@id")
+
+	created, err := s.findRecord(db, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordHistory(db, entity, id, "create", nil, created, actor); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// updateRecord applies already validated data to a live record. A non-empty
+// ifMatch must match the record's current ETag.
+func (s *Server) updateRecord(db *gorm.DB, entity config.EntityConfig, id interface{}, data map[string]interface{}, actor, ifMatch string) (map[string]interface{}, error) {
+	existing, err := s.findRecord(db, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIfMatch(ifMatch, entity, existing); err != nil {
+		return nil, err
+	}
+
+	data["updated_at"] = time.Now()
+	if err := hashPasswords(entity, data, isSystem(db)); err != nil {
+		return nil, err
+	}
+	query := s.table(db, entity).Where("id = ?", id)
+	if entity.Versioned {
+		// Guard against writes that landed since the record was read
+		data["version"] = gorm.Expr("version + 1")
+		query = query.Where("version = ?", existing["version"])
+	}
+
+	dbRes := query.Updates(data)
+	if dbRes.Error != nil {
+		return nil, dbRes.Error
+	}
+	if entity.Versioned && dbRes.RowsAffected == 0 {
+		return nil, errPreconditionFailed
+	}
+
+	updated, err := s.findRecord(db, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordHistory(db, entity, id, "update", existing, updated, actor); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// deleteRecord removes a live record, or stamps deleted_at for soft-delete
+// entities. Deleting a missing record is not an error unless a precondition
+// was given.
+func (s *Server) deleteRecord(db *gorm.DB, entity config.EntityConfig, id interface{}, actor, ifMatch string) error {
+	existing, err := s.findRecord(db, entity, id)
+	if errors.Is(err, errNotFound) {
+		if ifMatch != "" {
+			return errPreconditionFailed
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkIfMatch(ifMatch, entity, existing); err != nil {
+		return err
+	}
+
+	if entity.SoftDelete {
+		now := time.Now()
+		err = s.table(db, entity).Where("id = ?", id).Updates(touch(entity, map[string]interface{}{"deleted_at": now, "updated_at": now})).Error
+	} else {
+		err = s.table(db, entity).Where("id = ?", id).Delete(nil).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.recordHistory(db, entity, id, "delete", existing, nil, actor)
+}
+
+// touch bumps the version of versioned entities along with the changes, so
+// ETags taken before a soft delete or restore no longer match.
+func touch(entity config.EntityConfig, changes map[string]interface{}) map[string]interface{} {
+	if entity.Versioned {
+		changes["version"] = gorm.Expr("version + 1")
+	}
+	return changes
+}
+
+// restoreRecord clears deleted_at on a soft-deleted record.
+func (s *Server) restoreRecord(db *gorm.DB, entity config.EntityConfig, id interface{}, actor string) (map[string]interface{}, error) {
+	dbRes := s.tableWithTrashed(db, entity).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(touch(entity, map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()}))
+	if dbRes.Error != nil {
+		return nil, dbRes.Error
+	}
+	if dbRes.RowsAffected == 0 {
+		return nil, errNotFound
+	}
+
+	restored, err := s.findRecord(db, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordHistory(db, entity, id, "restore", nil, restored, actor); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError sends the standard error envelope:
+// {"success": false, "error": {"message": ..., "fields": [...]}}
+func writeError(w http.ResponseWriter, status int, message string, fields ...ValidationError) {
+	errBody := map[string]interface{}{"message": message}
+	if len(fields) > 0 {
+		errBody["fields"] = fields
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"success": false,
+		"error":   errBody,
+	})
+}
+
+func writeValidationErrors(w http.ResponseWriter, errs []ValidationError) {
+	writeError(w, http.StatusBadRequest, "validation failed", errs...)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+func tableName(entityName string) string {
+	return strings.ToLower(entityName) + "s"
+}
+
+type txKey struct{}
+
+// db returns the handle a request should use: the batch transaction when
+// the request runs inside POST /_batch, otherwise the database of the
+// request's tenant. The handle carries the request context, so scopes and
+// permissions can see the caller.
+func (s *Server) db(r *http.Request) *gorm.DB {
+	if tx, ok := r.Context().Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(r.Context())
+	}
+	return s.database(r).WithContext(r.Context())
+}
+
+// entityByName finds the configuration for an entity, e.g. the target of a
+// relation.
+func (s *Server) entityByName(name string) (config.EntityConfig, bool) {
+	for _, entity := range s.Config.Entities {
+		if strings.EqualFold(entity.Name, name) {
+			return entity, true
+		}
+	}
+	return config.EntityConfig{}, false
+}
+
+// table starts a query on the entity's table that only sees live rows.
+// Every read and write that targets existing records should go through it.
+func (s *Server) table(db *gorm.DB, entity config.EntityConfig) *gorm.DB {
+	query := s.tableWithTrashed(db, entity)
+	if entity.SoftDelete {
+		query = query.Where("deleted_at IS NULL")
+	}
+	return query
+}
+
+// tableWithTrashed is like table but also sees soft-deleted rows. Both
+// only see the rows of the caller's tenant, and the caller's own rows when
+// the entity has an owner field.
+func (s *Server) tableWithTrashed(db *gorm.DB, entity config.EntityConfig) *gorm.DB {
+	query := db.Table(tableName(entity.Name))
+	if tenant, scoped := s.tenantScope(db.Statement.Context); scoped {
+		query = query.Where(fmt.Sprintf("%s = ?", s.Config.TenantColumn()), tenant)
+	}
+	if owner, scoped := s.ownerScope(db.Statement.Context, entity); scoped {
+		query = query.Where(fmt.Sprintf("%s = ?", entity.OwnerField), owner)
+	}
+	return query
+}
+
+// relatedTable starts a query on a relation target, applying the target's
+// scopes when it is a configured entity.
+func (s *Server) relatedTable(db *gorm.DB, entityName string) *gorm.DB {
+	if target, ok := s.entityByName(entityName); ok {
+		return s.table(db, target)
+	}
+	return db.Table(tableName(entityName))
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"gorm.io/gorm"
+)
+
+// seedRefKey names a seed record so later records can point at it.
+const seedRefKey = "_ref"
+
+// SeedReport counts what a seed run did per entity.
+type SeedReport struct {
+	Created   map[string]int
+	Updated   map[string]int
+	Unchanged map[string]int
+}
+
+// Seed inserts fixture records in one transaction. Records that already
+// exist, matched on their natural key, are updated instead of inserted, so
+// running the same seeds twice leaves the database unchanged. String values
+// of the form "@name" resolve to the id of the record seeded with that
+// _ref; "@@" escapes a literal "@".
+func (s *Server) Seed(seeds []config.SeedConfig) (*SeedReport, error) {
+	report := &SeedReport{Created: map[string]int{}, Updated: map[string]int{}, Unchanged: map[string]int{}}
+	refs := map[string]interface{}{}
+
+	err := s.system().Transaction(func(tx *gorm.DB) error {
+		for _, seed := range seeds {
+			entity, ok := s.entityByName(seed.Entity)
+			if !ok {
+				return fmt.Errorf("seeds: unknown entity %s", seed.Entity)
+			}
+			for i, record := range seed.Records {
+				outcome, err := s.seedRecord(tx, entity, seed.Key, record, refs)
+				if err != nil {
+					return fmt.Errorf("seeds: %s record %d: %w", entity.Name, i+1, err)
+				}
+				switch outcome {
+				case "created":
+					report.Created[entity.Name]++
+				case "updated":
+					report.Updated[entity.Name]++
+				default:
+					report.Unchanged[entity.Name]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *Server) seedRecord(db *gorm.DB, entity config.EntityConfig, key []string, record map[string]interface{}, refs map[string]interface{}) (string, error) {
+	declared := make(map[string]bool, len(entity.Fields))
+	passwords := map[string]bool{}
+	for _, field := range entity.Fields {
+		declared[field.Name] = true
+		passwords[field.Name] = field.Type == "password"
+	}
+
+	data := make(map[string]interface{}, len(record))
+	ref := ""
+	for name, val := range record {
+		if name == seedRefKey {
+			ref = fmt.Sprint(val)
+			continue
+		}
+		resolved, err := resolveSeedValue(val, refs)
+		if err != nil {
+			return "", err
+		}
+		data[name] = resolved
+	}
+
+	// Relations may be referenced by their expand key, e.g. user: "@alice"
+	for _, rel := range entity.Relations {
+		name := relationKey(rel)
+		if val, ok := data[name]; ok && rel.Type == "belongs_to" && !declared[name] {
+			data[rel.Field] = val
+			delete(data, name)
+		}
+	}
+
+	if errs := sanitizeInput(entity, data); len(errs) > 0 {
+		return "", seedErrors(errs)
+	}
+
+	if len(key) == 0 {
+		key = naturalKey(entity, data)
+	}
+	query := s.table(db, entity)
+	for _, name := range key {
+		val, ok := data[name]
+		if !ok {
+			return "", fmt.Errorf("missing natural key field %s", name)
+		}
+		query = query.Where(fmt.Sprintf("%s = ?", name), val)
+	}
+
+	existing := map[string]interface{}{}
+	res := query.Limit(1).Scan(&existing)
+	if res.Error != nil {
+		return "", res.Error
+	}
+
+	var stored map[string]interface{}
+	outcome := "unchanged"
+	if res.RowsAffected == 0 {
+		if errs := s.validateData(db, entity, data); len(errs) > 0 {
+			return "", seedErrors(errs)
+		}
+		created, err := s.createRecord(db, entity, data, "skema seed")
+		if err != nil {
+			return "", err
+		}
+		stored, outcome = created, "created"
+	} else {
+		stored = existing
+		changes := map[string]interface{}{}
+		for name, val := range data {
+			// Passwords are stored hashed
+			if passwords[name] && checkPassword(existing[name], fmt.Sprint(val)) {
+				continue
+			}
+			if !sameValue(val, existing[name]) {
+				changes[name] = val
+			}
+		}
+		if len(changes) > 0 {
+			if errs := s.validatePatch(db, entity, changes, existing); len(errs) > 0 {
+				return "", seedErrors(errs)
+			}
+			updated, err := s.updateRecord(db, entity, existing["id"], changes, "skema seed", "")
+			if err != nil {
+				return "", err
+			}
+			stored, outcome = updated, "updated"
+		}
+	}
+
+	if ref != "" {
+		if _, taken := refs[ref]; taken {
+			return "", fmt.Errorf("duplicate _ref %q", ref)
+		}
+		refs[ref] = stored["id"]
+	}
+	return outcome, nil
+}
+
+// naturalKey picks the fields that identify a seed record: the entity's
+// unique fields when the record sets them, otherwise every field it sets.
+func naturalKey(entity config.EntityConfig, data map[string]interface{}) []string {
+	var key []string
+	for _, field := range entity.Fields {
+		if _, ok := data[field.Name]; ok && field.Unique {
+			key = append(key, field.Name)
+		}
+	}
+	if len(key) > 0 {
+		return key
+	}
+	for _, field := range entity.Fields {
+		if _, ok := data[field.Name]; ok {
+			key = append(key, field.Name)
+		}
+	}
+	return key
+}
+
+func resolveSeedValue(val interface{}, refs map[string]interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case string:
+		if strings.HasPrefix(v, "@@") {
+			return v[1:], nil
+		}
+		if strings.HasPrefix(v, "@") {
+			id, ok := refs[v[1:]]
+			if !ok {
+				return nil, fmt.Errorf("unknown reference %s", v)
+			}
+			return id, nil
+		}
+	case time.Time:
+		// Unquoted YAML dates and timestamps
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return v.Format("2006-01-02"), nil
+		}
+		return v.Format(time.RFC3339), nil
+	}
+	return val, nil
+}
+
+// sameValue compares a seed value with a stored column value.
+func sameValue(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func seedErrors(errs []ValidationError) error {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Message
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSeed(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "User",
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string", Required: true},
+					{Name: "email", Type: "string", Unique: true},
+				},
+			},
+			{
+				Name: "Post",
+				Fields: []config.FieldConfig{
+					{Name: "title", Type: "string", Required: true},
+					{Name: "published_on", Type: "string", Format: "date"},
+					{Name: "user_id", Type: "int", Required: true},
+				},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "User", Field: "user_id"}},
+			},
+		},
+	}
+
+	os.Remove("test_seed.db")
+	database, err := db.InitDB(cfg, "test_seed.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_seed.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	var fixtures struct {
+		Seeds []config.SeedConfig `yaml:"seeds"`
+	}
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+seeds:
+  - entity: User
+    records:
+      - _ref: alice
+        name: Alice
+        email: alice@example.com
+      - _ref: bob
+        name: Bob
+        email: bob@example.com
+  - entity: Post
+    key: [title]
+    records:
+      - title: Hello
+        published_on: 2024-05-01
+        user: "@bob"
+      - title: "@@mentions"
+        user_id: "@alice"
+`), &fixtures))
+
+	report, err := srv.Seed(fixtures.Seeds)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Created["User"])
+	assert.Equal(t, 2, report.Created["Post"])
+
+	var post map[string]interface{}
+	database.Table("posts").Where("title = ?", "Hello").Take(&post)
+	assert.Equal(t, int64(2), post["user_id"])
+	assert.Equal(t, "2024-05-01", post["published_on"])
+	var n int64
+	database.Table("posts").Where("title = ?", "@mentions").Count(&n)
+	assert.Equal(t, int64(1), n)
+
+	t.Run("Idempotent", func(t *testing.T) {
+		fixtures.Seeds[0].Records[1]["name"] = "Robert"
+
+		report, err := srv.Seed(fixtures.Seeds)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.Created["User"]+report.Created["Post"])
+		assert.Equal(t, 1, report.Updated["User"])
+		assert.Equal(t, 1, report.Unchanged["User"])
+		assert.Equal(t, 2, report.Unchanged["Post"])
+
+		var users int64
+		database.Table("users").Count(&users)
+		assert.Equal(t, int64(2), users)
+	})
+
+	t.Run("FailsAtomically", func(t *testing.T) {
+		_, err := srv.Seed([]config.SeedConfig{
+			{Entity: "User", Records: []map[string]interface{}{{"name": "Carol", "email": "carol@example.com"}}},
+			{Entity: "Post", Records: []map[string]interface{}{{"title": "Orphan", "user": "@nobody"}}},
+		})
+		assert.ErrorContains(t, err, "unknown reference @nobody")
+
+		var users int64
+		database.Table("users").Count(&users)
+		assert.Equal(t, int64(2), users)
+	})
+}
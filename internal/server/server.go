@@ -1,11 +1,15 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
-	"regexp"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,28 +18,77 @@ import (
 	"gorm.io/gorm"
 )
 
+// shutdownTimeout is how long Start waits for requests in flight on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 type Server struct {
 	Config *config.Config
 	DB     *gorm.DB
 	Router *chi.Mux
+
+	plans   map[string]*validationPlan
+	auth    *authenticator
+	tenant  string // set by Tenant for command line work on one tenant
+	tenants *tenantDatabases
+	limiter *rateLimiter
 }
 
-func NewServer(cfg *config.Config, db *gorm.DB) *Server {
+func NewServer(cfg *config.Config, db *gorm.DB) (*Server, error) {
 	s := &Server{
 		Config: cfg,
 		DB:     db,
 		Router: chi.NewRouter(),
+		plans:  make(map[string]*validationPlan),
+	}
+
+	for _, entity := range cfg.Entities {
+		plan, err := buildValidationPlan(entity)
+		if err != nil {
+			return nil, err
+		}
+		s.plans[entity.Name] = plan
+
+		if err := checkPermissions(entity); err != nil {
+			return nil, err
+		}
+		if err := checkOwnerField(cfg, entity); err != nil {
+			return nil, err
+		}
+	}
+
+	auth, err := newAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.auth = auth
+
+	if err := s.checkTenancy(); err != nil {
+		return nil, err
+	}
+
+	limiter, err := newRateLimiter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if limiter != nil && limiter.persist && db != nil {
+		if err := limiter.load(db); err != nil {
+			return nil, fmt.Errorf("rate_limit: loading buckets: %w", err)
+		}
 	}
+	s.limiter = limiter
 
 	s.setupMiddleware()
 	s.setupRoutes()
 
-	return s
+	return s, nil
 }
 
 func (s *Server) setupMiddleware() {
 	s.Router.Use(middleware.Logger)
 	s.Router.Use(middleware.Recoverer)
+	s.Router.Use(s.authenticate)
+	s.Router.Use(s.resolveTenant)
+	s.Router.Use(s.rateLimit)
 }
 
 func (s *Server) setupRoutes() {
@@ -44,44 +97,54 @@ func (s *Server) setupRoutes() {
 		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Welcome to %s", s.Config.Server.Name)})
 	})
 
+	s.Router.With(s.authorizeAny, s.requireTenant).Post("/_batch", s.handleBatch)
+	if s.auth != nil && s.auth.users != nil {
+		s.setupAccountRoutes()
+	}
+
 	for _, entity := range s.Config.Entities {
 		s.setupEntityRoutes(entity)
 	}
 }
 
 func (s *Server) setupEntityRoutes(entity config.EntityConfig) {
-	path := "/" + strings.ToLower(entity.Name) + "s"
-	tableName := strings.ToLower(entity.Name) + "s"
+	path := "/" + tableName(entity.Name)
 
 	s.Router.Route(path, func(r chi.Router) {
+		r.Use(s.authorize(entity))
+		r.Use(s.requireTenant)
+		if entity.OwnerField != "" {
+			r.Use(s.requireCaller)
+		}
+
 		// List
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			query := s.DB.Table(tableName)
+		r.With(s.permit(entity, "list")).Get("/", func(w http.ResponseWriter, r *http.Request) {
+			db := s.db(r)
 
 			// 1. Filtering
-			for _, field := range entity.Fields {
-				val := r.URL.Query().Get(field.Name)
-				if val != "" {
-					if field.Type == "string" || field.Type == "text" {
-						query = query.Where(fmt.Sprintf("%s LIKE ?", field.Name), "%"+val+"%")
-					} else {
-						query = query.Where(fmt.Sprintf("%s = ?", field.Name), val)
-					}
-				}
-			}
+			query := s.filteredQuery(db, entity, r)
 
 			// 2. Sorting
-			sort := r.URL.Query().Get("sort") // format: field:asc or field:desc
-			if sort != "" {
-				parts := strings.Split(sort, ":")
-				if len(parts) == 2 {
-					query = query.Order(fmt.Sprintf("%s %s", parts[0], parts[1]))
-				} else {
-					query = query.Order(parts[0])
+			used := filterFields(entity, r)
+			order := "created_at desc"
+			if sort := r.URL.Query().Get("sort"); sort != "" { // format: field:asc or field:desc
+				field, dir, ok := parseSort(entity, sort)
+				if !ok {
+					writeError(w, http.StatusBadRequest, "invalid sort", ValidationError{
+						Field:   "sort",
+						Rule:    "sort",
+						Message: "sort must be a field, optionally followed by :asc or :desc",
+					})
+					return
 				}
-			} else {
-				query = query.Order("created_at desc")
+				used = append(used, field)
+				order = field + " " + dir
+			}
+			if denied := s.unreadable(db, entity, used); len(denied) > 0 {
+				writeError(w, http.StatusForbidden, errForbidden.Error(), denied...)
+				return
 			}
+			query = query.Order(order)
 
 			// 3. Pagination & Count
 			limitStr := r.URL.Query().Get("limit")
@@ -96,16 +159,32 @@ func (s *Server) setupEntityRoutes(entity config.EntityConfig) {
 				fmt.Sscanf(offsetStr, "%d", &offset)
 			}
 
+			// Exports stream every matching row unless a page is asked for
+			if format := exportFormat(r); format != "" {
+				if columns := s.readableColumns(db, entity); columns != nil {
+					query = query.Select(columns)
+				}
+				if limitStr != "" {
+					query = query.Limit(limit)
+				}
+				if offsetStr != "" {
+					query = query.Offset(offset)
+				}
+				writeExport(w, query, format, tableName(entity.Name))
+				return
+			}
+
 			var total int64
 			query.Count(&total)
 
 			results := []map[string]interface{}{}
 			if err := query.Limit(limit).Offset(offset).Find(&results).Error; err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeError(w, http.StatusInternalServerError, err.Error())
 				return
 			}
 
-			s.expandData(entity, results, r.URL.Query().Get("expand"))
+			s.expandData(db, entity, results, r.URL.Query().Get("expand"))
+			s.redact(db, entity, results...)
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -119,48 +198,114 @@ func (s *Server) setupEntityRoutes(entity config.EntityConfig) {
 			})
 		})
 
+		r.With(s.permit(entity, "list")).Get("/_aggregate", s.handleAggregate(entity))
+		r.With(s.permit(entity, "list")).Get("/_facets", s.handleFacets(entity))
+		r.With(s.permit(entity, "create")).Post("/_import", s.handleImport(entity))
+
 		// Create
-		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		r.With(s.permit(entity, "create")).Post("/", func(w http.ResponseWriter, r *http.Request) {
+			db := s.db(r)
 			var data map[string]interface{}
 			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				writeError(w, http.StatusBadRequest, "Invalid JSON")
 				return
 			}
 
-			if errMsg := s.validateData(entity, data); errMsg != "" {
-				http.Error(w, errMsg, http.StatusBadRequest)
+			// Nested belongs_to / has_many payloads are created in the same transaction
+			var created map[string]interface{}
+			var errs []ValidationError
+			err := db.Transaction(func(tx *gorm.DB) error {
+				g := &graphWriter{s: s, db: tx, actor: requestActor(r)}
+				var err error
+				created, err = g.create(entity, data, "", "")
+				if err != nil {
+					return err
+				}
+				if len(g.denied) > 0 {
+					errs = g.denied
+					return errForbidden
+				}
+				if len(g.errs) > 0 {
+					errs = g.errs
+					return errValidation
+				}
+				return nil
+			})
+			if err == errForbidden {
+				writeError(w, http.StatusForbidden, err.Error(), errs...)
 				return
 			}
-
-			now := time.Now()
-			data["created_at"] = now
-			data["updated_at"] = now
-
-			if err := s.DB.Table(tableName).Create(&data).Error; err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if err == errValidation {
+				writeValidationErrors(w, errs)
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
 				return
 			}
 
+			w.Header().Set("ETag", etag(entity, created))
+			s.redact(db, entity, created)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusCreated)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": true,
-				"data":    data,
+				"data":    created,
 			})
 		})
 
 		// Get by ID
-		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r.With(s.permit(entity, "get")).Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			db := s.db(r)
 			id := chi.URLParam(r, "id")
-			result := make(map[string]interface{})
-			dbRes := s.DB.Table(tableName).Where("id = ?", id).Scan(&result)
-			if dbRes.Error != nil || dbRes.RowsAffected == 0 {
-				http.Error(w, "Not Found", http.StatusNotFound)
+
+			// Point-in-time lookup from the history log
+			if asOfStr := r.URL.Query().Get("as_of"); asOfStr != "" {
+				if !entity.History {
+					writeError(w, http.StatusBadRequest, "history is not enabled for "+entity.Name)
+					return
+				}
+				asOf, ok := parseAsOf(asOfStr)
+				if !ok {
+					writeError(w, http.StatusBadRequest, "as_of must be an RFC 3339 timestamp or a date")
+					return
+				}
+				result, err := s.recordAsOf(db, entity, id, asOf)
+				if err == nil && !s.visible(db, entity, id) {
+					err = errNotFound
+				}
+				if err == errNotFound {
+					writeError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				if err != nil {
+					writeError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+				s.redact(db, entity, result)
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"success": true,
+					"data":    result,
+				})
+				return
+			}
+
+			result, err := s.findRecord(db, entity, id)
+			if err != nil {
+				writeError(w, http.StatusNotFound, "Not Found")
+				return
+			}
+
+			tag := etag(entity, result)
+			w.Header().Set("ETag", tag)
+			if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, tag, true) {
+				w.WriteHeader(http.StatusNotModified)
 				return
 			}
 
 			results := []map[string]interface{}{result}
-			s.expandData(entity, results, r.URL.Query().Get("expand"))
+			s.expandData(db, entity, results, r.URL.Query().Get("expand"))
+			s.redact(db, entity, results...)
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -169,115 +314,208 @@ func (s *Server) setupEntityRoutes(entity config.EntityConfig) {
 			})
 		})
 
-		// Update by ID
-		r.Put("/{id}", func(w http.ResponseWriter, r *http.Request) {
-			id := chi.URLParam(r, "id")
-			var data map[string]interface{}
-			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-				http.Error(w, "Invalid JSON", http.StatusBadRequest)
-				return
-			}
+		// Update by ID: PUT replaces the payload, PATCH only touches the keys sent
+		update := func(partial bool) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				db := s.db(r)
+				id := chi.URLParam(r, "id")
+				existing, err := s.findRecord(db, entity, id)
+				if err != nil {
+					writeError(w, http.StatusNotFound, "Not Found")
+					return
+				}
 
-			if errMsg := s.validateData(entity, data); errMsg != "" {
-				http.Error(w, errMsg, http.StatusBadRequest)
-				return
-			}
+				var data map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+					writeError(w, http.StatusBadRequest, "Invalid JSON")
+					return
+				}
 
-			data["updated_at"] = time.Now()
+				s.dropReadOnly(db, entity, data)
+				denied := s.writeDenied(db, entity, data, "update")
+				denied = append(denied, s.stampOwner(db, entity, data, "update")...)
+				if len(denied) > 0 {
+					writeError(w, http.StatusForbidden, errForbidden.Error(), denied...)
+					return
+				}
 
-			if err := s.DB.Table(tableName).Where("id = ?", id).Updates(data).Error; err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+				errs := sanitizeInput(entity, data)
+				if partial {
+					errs = append(errs, s.validatePatch(db, entity, data, existing)...)
+				} else {
+					errs = append(errs, s.validateReplace(db, entity, data, existing)...)
+				}
+				if len(errs) > 0 {
+					writeValidationErrors(w, errs)
+					return
+				}
 
-			result := make(map[string]interface{})
-			s.DB.Table(tableName).Where("id = ?", id).Scan(&result)
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": true,
-				"data":    result,
-			})
-		})
+				var updated map[string]interface{}
+				err = db.Transaction(func(tx *gorm.DB) error {
+					var err error
+					updated, err = s.updateRecord(tx, entity, id, data, requestActor(r), r.Header.Get("If-Match"))
+					return err
+				})
+				if err == errPreconditionFailed {
+					writeError(w, http.StatusPreconditionFailed, "record was modified; fetch it again and retry")
+					return
+				}
+				if err != nil {
+					writeError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+
+				w.Header().Set("ETag", etag(entity, updated))
+				s.redact(db, entity, updated)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"data":    updated,
+				})
+			}
+		}
+		r.With(s.permit(entity, "update")).Put("/{id}", update(false))
+		r.With(s.permit(entity, "update")).Patch("/{id}", update(true))
 
 		// Delete by ID
-		r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r.With(s.permit(entity, "delete")).Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			db := s.db(r)
 			id := chi.URLParam(r, "id")
-			if err := s.DB.Table(tableName).Where("id = ?", id).Delete(nil).Error; err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return s.deleteRecord(tx, entity, id, requestActor(r), r.Header.Get("If-Match"))
+			})
+			if err == errPreconditionFailed {
+				writeError(w, http.StatusPreconditionFailed, "record was modified; fetch it again and retry")
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
 				return
 			}
 			w.WriteHeader(http.StatusNoContent)
 		})
-	})
-}
 
-func (s *Server) validateData(entity config.EntityConfig, data map[string]interface{}) string {
-	for _, field := range entity.Fields {
-		val, exists := data[field.Name]
-
-		// Required check
-		if field.Required && (!exists || val == nil || val == "") {
-			return fmt.Sprintf("field '%s' is required", field.Name)
+		// Restore a soft-deleted record
+		if entity.SoftDelete {
+			r.With(s.permit(entity, "update")).Post("/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+				db := s.db(r)
+				id := chi.URLParam(r, "id")
+				var restored map[string]interface{}
+				err := db.Transaction(func(tx *gorm.DB) error {
+					var err error
+					restored, err = s.restoreRecord(tx, entity, id, requestActor(r))
+					return err
+				})
+				if err == errNotFound {
+					writeError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				if err != nil {
+					writeError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+				s.redact(db, entity, restored)
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"success": true,
+					"data":    restored,
+				})
+			})
 		}
 
-		if exists && val != nil {
-			// Min/Max for numbers
-			if field.Type == "int" || field.Type == "float" {
-				var num float64
-				switch v := val.(type) {
-				case int:
-					num = float64(v)
-				case float64:
-					num = v
-				case int64:
-					num = float64(v)
+		// Change log of a record
+		if entity.History {
+			r.With(s.permit(entity, "get")).Get("/{id}/history", func(w http.ResponseWriter, r *http.Request) {
+				db := s.db(r)
+				id := chi.URLParam(r, "id")
+				rows, err := s.listHistory(db, entity, id)
+				if err != nil {
+					writeError(w, http.StatusInternalServerError, err.Error())
+					return
 				}
-
-				if field.Min != nil && num < float64(*field.Min) {
-					return fmt.Sprintf("field '%s' must be at least %d", field.Name, *field.Min)
+				if len(rows) == 0 || !s.visible(db, entity, id) {
+					writeError(w, http.StatusNotFound, "Not Found")
+					return
 				}
-				if field.Max != nil && num > float64(*field.Max) {
-					return fmt.Sprintf("field '%s' must be at most %d", field.Name, *field.Max)
+				for _, row := range rows {
+					for _, key := range []string{"old_values", "new_values"} {
+						if values, ok := row[key].(map[string]interface{}); ok {
+							s.redact(db, entity, values)
+						}
+					}
 				}
-			}
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"success": true,
+					"data":    rows,
+				})
+			})
+		}
+	})
+}
 
-			// Pattern check for strings
-			if field.Pattern != "" {
-				res, _ := regexp.MatchString(field.Pattern, fmt.Sprintf("%v", val))
-				if !res {
-					return fmt.Sprintf("field '%s' does not match pattern '%s'", field.Name, field.Pattern)
-				}
-			}
+// filteredQuery starts a list query with the ?trashed mode and the per-field
+// filters of the request applied.
+func (s *Server) filteredQuery(db *gorm.DB, entity config.EntityConfig, r *http.Request) *gorm.DB {
+	query := s.table(db, entity)
+	if entity.SoftDelete {
+		switch r.URL.Query().Get("trashed") {
+		case "only":
+			query = s.tableWithTrashed(db, entity).Where("deleted_at IS NOT NULL")
+		case "with":
+			query = s.tableWithTrashed(db, entity)
+		}
+	}
 
-			// Format checks
-			if field.Format == "email" {
-				emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-				if !emailRegex.MatchString(fmt.Sprintf("%v", val)) {
-					return fmt.Sprintf("field '%s' must be a valid email", field.Name)
-				}
+	for _, field := range entity.Fields {
+		val := r.URL.Query().Get(field.Name)
+		if val != "" && !secret(field) {
+			if field.Type == "string" || field.Type == "text" {
+				query = query.Where(fmt.Sprintf("%s LIKE ?", field.Name), "%"+val+"%")
+			} else {
+				query = query.Where(fmt.Sprintf("%s = ?", field.Name), coerceValue(field.Type, val))
 			}
 		}
 	}
 
-	// Relationship Validation
-	for _, rel := range entity.Relations {
-		if rel.Type == "belongs_to" {
-			val, exists := data[rel.Field]
-			if exists && val != nil {
-				targetTable := strings.ToLower(rel.Entity) + "s"
-				var count int64
-				s.DB.Table(targetTable).Where("id = ?", val).Count(&count)
-				if count == 0 {
-					return fmt.Sprintf("related %s with id %v does not exist", rel.Entity, val)
-				}
-			}
+	return query
+}
+
+// filterFields lists the fields the request filters on.
+func filterFields(entity config.EntityConfig, r *http.Request) []string {
+	var names []string
+	for _, field := range entity.Fields {
+		if r.URL.Query().Get(field.Name) != "" {
+			names = append(names, field.Name)
 		}
 	}
+	return names
+}
 
-	return ""
+// parseSort reads ?sort=field or ?sort=field:asc|desc. Only the entity's
+// columns can be sorted on.
+func parseSort(entity config.EntityConfig, sort string) (string, string, bool) {
+	field, dir, _ := strings.Cut(sort, ":")
+	dir = strings.ToLower(dir)
+	if dir == "" {
+		dir = "asc"
+	}
+	if dir != "asc" && dir != "desc" {
+		return "", "", false
+	}
+	if systemColumns[field] && (field != "deleted_at" || entity.SoftDelete) {
+		return field, dir, true
+	}
+	if field == "version" && entity.Versioned {
+		return field, dir, true
+	}
+	for _, f := range entity.Fields {
+		if f.Name == field {
+			return field, dir, true
+		}
+	}
+	return "", "", false
 }
 
-func (s *Server) expandData(entity config.EntityConfig, results []map[string]interface{}, expandParam string) {
+func (s *Server) expandData(db *gorm.DB, entity config.EntityConfig, results []map[string]interface{}, expandParam string) {
 	if expandParam == "" {
 		return
 	}
@@ -296,13 +534,12 @@ func (s *Server) expandData(entity config.EntityConfig, results []map[string]int
 		}
 
 		if relation != nil {
-			targetTable := strings.ToLower(relation.Entity) + "s"
 			for i := range results {
 				if relation.Type == "belongs_to" {
 					targetID := results[i][relation.Field]
 					if targetID != nil {
 						targetData := make(map[string]interface{})
-						dbRes := s.DB.Table(targetTable).Where("id = ?", targetID).Scan(&targetData)
+						dbRes := s.relatedTable(db, relation.Entity).Where("id = ?", targetID).Scan(&targetData)
 						if dbRes.Error == nil && dbRes.RowsAffected > 0 {
 							results[i][strings.ToLower(relation.Entity)] = targetData
 						}
@@ -311,7 +548,7 @@ func (s *Server) expandData(entity config.EntityConfig, results []map[string]int
 					currentID := results[i]["id"]
 					if currentID != nil {
 						targetRecords := []map[string]interface{}{}
-						if err := s.DB.Table(targetTable).Where(fmt.Sprintf("%s = ?", relation.Field), currentID).Find(&targetRecords).Error; err == nil {
+						if err := s.relatedTable(db, relation.Entity).Where(fmt.Sprintf("%s = ?", relation.Field), currentID).Find(&targetRecords).Error; err == nil {
 							key := strings.ToLower(relation.Entity) + "s"
 							results[i][key] = targetRecords
 						}
@@ -322,8 +559,46 @@ func (s *Server) expandData(entity config.EntityConfig, results []map[string]int
 	}
 }
 
+// Start serves the API until the process gets SIGINT or SIGTERM, then lets
+// requests in flight finish.
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.Config.Server.Port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Server starting on %s\n", addr)
-	return http.ListenAndServe(addr, s.Router)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return s.serve(ctx, ln)
+}
+
+// serve serves the API on ln until ctx is done. Persisted rate limit
+// buckets are saved periodically and once more after the last request.
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	persist := s.limiter != nil && s.limiter.persist
+	if persist {
+		go s.limiter.saveEvery(ctx, s.DB, rateLimitSaveInterval)
+	}
+
+	srv := &http.Server{Handler: s.Router}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if persist {
+		if saveErr := s.limiter.save(s.DB); saveErr != nil && err == nil {
+			err = fmt.Errorf("rate_limit: saving buckets: %w", saveErr)
+		}
+	}
+	return err
 }
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/iamajraj/skema/internal/config"
 	"github.com/iamajraj/skema/internal/db"
@@ -35,7 +36,8 @@ func TestServerCRUD(t *testing.T) {
 	database, err := db.InitDB(cfg, "test_skema.db")
 	assert.NoError(t, err)
 
-	srv := NewServer(cfg, database)
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
 
 	// Test Case 1: Create User (Success)
 	userData := map[string]interface{}{
@@ -77,3 +79,455 @@ func TestServerCRUD(t *testing.T) {
 	// Clean up
 	os.Remove("test_skema.db")
 }
+
+func TestValidationReportsAllErrors(t *testing.T) {
+	minAge := 18
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "User",
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string", Required: true},
+					{Name: "email", Type: "string", Format: "email"},
+					{Name: "age", Type: "int", Min: &minAge},
+					{Name: "score", Type: "int"},
+				},
+			},
+		},
+	}
+
+	os.Remove("test_validation.db")
+	database, err := db.InitDB(cfg, "test_validation.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_validation.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"email": "not-an-email",
+		"age":   10,
+		"score": "lots",
+	})
+	req := httptest.NewRequest("POST", "/users", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp struct {
+		Success bool `json:"success"`
+		Error   struct {
+			Message string            `json:"message"`
+			Fields  []ValidationError `json:"fields"`
+		} `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Success)
+
+	rules := map[string]string{}
+	for _, f := range resp.Error.Fields {
+		rules[f.Field] = f.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"name":  "required",
+		"email": "format",
+		"age":   "min",
+		"score": "type",
+	}, rules)
+}
+
+func TestStrictInputAndCoercion(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "Item",
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string", Required: true},
+					{Name: "qty", Type: "int"},
+					{Name: "active", Type: "bool"},
+				},
+			},
+			{
+				Name:          "Note",
+				UnknownFields: "strip",
+				Fields:        []config.FieldConfig{{Name: "body", Type: "text"}},
+			},
+		},
+	}
+
+	os.Remove("test_strict.db")
+	database, err := db.InitDB(cfg, "test_strict.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_strict.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	// Unknown keys are rejected by default
+	body, _ := json.Marshal(map[string]interface{}{"name": "Widget", "colour": "red"})
+	w := httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/items", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"rule":"unknown"`)
+
+	// Strings are coerced and system columns are ignored
+	body, _ = json.Marshal(map[string]interface{}{"id": 999, "name": "Widget", "qty": "42", "active": "true"})
+	w = httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/items", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created map[string]interface{}
+	database.Table("items").Where("name = ?", "Widget").Take(&created)
+	assert.NotEqual(t, int64(999), created["id"])
+	assert.EqualValues(t, 42, created["qty"])
+
+	// Entities configured to strip drop unknown keys silently
+	body, _ = json.Marshal(map[string]interface{}{"body": "hello", "extra": 1})
+	w = httptest.NewRecorder()
+	srv.Router.ServeHTTP(w, httptest.NewRequest("POST", "/notes", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestSoftDelete(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:       "Category",
+				SoftDelete: true,
+				Versioned:  true,
+				Fields:     []config.FieldConfig{{Name: "name", Type: "string", Required: true}},
+				Relations:  []config.RelationConfig{{Type: "has_many", Entity: "Product", Field: "category_id"}},
+			},
+			{
+				Name:      "Product",
+				Fields:    []config.FieldConfig{{Name: "name", Type: "string"}, {Name: "category_id", Type: "int"}},
+				Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Category", Field: "category_id"}},
+			},
+		},
+	}
+
+	os.Remove("test_soft_delete.db")
+	database, err := db.InitDB(cfg, "test_soft_delete.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_soft_delete.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	doIf := func(method, path, ifMatch string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return doIf(method, path, "", body)
+	}
+
+	w := do("POST", "/categorys", map[string]interface{}{"name": "Books"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	before := w.Header().Get("ETag")
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/categorys/1", nil).Code)
+
+	// The row is still in the table but hidden from the API
+	var count int64
+	database.Table("categorys").Count(&count)
+	assert.EqualValues(t, 1, count)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/categorys/1", nil).Code)
+	assert.Contains(t, do("GET", "/categorys", nil).Body.String(), `"total":0`)
+	w = do("GET", "/categorys?trashed=only", nil)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+	assert.Contains(t, w.Body.String(), `"version":2`)
+	var trashed struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &trashed)
+	assert.Equal(t, trashed.Data[0]["deleted_at"], trashed.Data[0]["updated_at"])
+
+	// Deleted parents can't be referenced
+	w = do("POST", "/products", map[string]interface{}{"name": "Novel", "category_id": 1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"rule":"relation"`)
+
+	// Restore brings it back
+	assert.Equal(t, http.StatusOK, do("POST", "/categorys/1/restore", nil).Code)
+	w = do("GET", "/categorys/1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/categorys/1/restore", nil).Code)
+
+	// Deleting and restoring count as changes, so older ETags no longer match
+	after := w.Header().Get("ETag")
+	assert.NotEqual(t, before, after)
+	assert.Contains(t, w.Body.String(), `"version":3`)
+	assert.Equal(t, http.StatusPreconditionFailed, doIf("PATCH", "/categorys/1", before, map[string]interface{}{"name": "Novels"}).Code)
+	assert.Equal(t, http.StatusOK, doIf("PATCH", "/categorys/1", after, map[string]interface{}{"name": "Novels"}).Code)
+}
+
+func TestHistory(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:    "Order",
+				History: true,
+				Fields:  []config.FieldConfig{{Name: "total_amount", Type: "float"}},
+			},
+		},
+	}
+
+	os.Remove("test_history.db")
+	database, err := db.InitDB(cfg, "test_history.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_history.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("X-Actor", "alice")
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/orders", map[string]interface{}{"total_amount": 10}).Code)
+	afterCreate := time.Now()
+	assert.Equal(t, http.StatusOK, do("PUT", "/orders/1", map[string]interface{}{"total_amount": 25}).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/orders/1", nil).Code)
+
+	w := do("GET", "/orders/1/history", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var histResp struct {
+		Data []struct {
+			Action    string                 `json:"action"`
+			Actor     string                 `json:"actor"`
+			OldValues map[string]interface{} `json:"old_values"`
+			NewValues map[string]interface{} `json:"new_values"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &histResp))
+	assert.Len(t, histResp.Data, 3)
+	assert.Equal(t, "update", histResp.Data[1].Action)
+	assert.Equal(t, "alice", histResp.Data[1].Actor)
+	assert.EqualValues(t, 10, histResp.Data[1].OldValues["total_amount"])
+	assert.EqualValues(t, 25, histResp.Data[1].NewValues["total_amount"])
+	assert.Equal(t, "delete", histResp.Data[2].Action)
+
+	// The record is gone now, but its state at an earlier time is not
+	assert.Equal(t, http.StatusNotFound, do("GET", "/orders/1", nil).Code)
+	w = do("GET", "/orders/1?as_of="+afterCreate.UTC().Format(time.RFC3339Nano), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total_amount":10`)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/orders/1?as_of="+time.Now().UTC().Format(time.RFC3339Nano), nil).Code)
+}
+
+func TestETagPreconditions(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:      "Product",
+				Versioned: true,
+				Fields: []config.FieldConfig{
+					{Name: "name", Type: "string", Required: true},
+					{Name: "price", Type: "float"},
+				},
+			},
+			{
+				Name:   "Note",
+				Fields: []config.FieldConfig{{Name: "body", Type: "text"}},
+			},
+		},
+	}
+
+	os.Remove("test_etag.db")
+	database, err := db.InitDB(cfg, "test_etag.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_etag.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/products", map[string]interface{}{"name": "Lamp", "price": 20}, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+
+	w = do("GET", "/products/1", nil, map[string]string{"If-None-Match": `"v1"`})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// First admin wins, second gets 412 instead of silently overwriting
+	w = do("PATCH", "/products/1", map[string]interface{}{"price": 25}, map[string]string{"If-Match": `"v1"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"v2"`, w.Header().Get("ETag"))
+
+	w = do("PUT", "/products/1", map[string]interface{}{"name": "Lamp", "price": 30}, map[string]string{"If-Match": `"v1"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = do("DELETE", "/products/1", nil, map[string]string{"If-Match": `"v1"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = do("GET", "/products/1", nil, nil)
+	assert.Contains(t, w.Body.String(), `"price":25`)
+
+	// Without a version column the tag follows updated_at
+	w = do("POST", "/notes", map[string]interface{}{"body": "hi"}, nil)
+	tag := w.Header().Get("ETag")
+	assert.NotEmpty(t, tag)
+	w = do("PATCH", "/notes/1", map[string]interface{}{"body": "hello"}, map[string]string{"If-Match": tag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, tag, w.Header().Get("ETag"))
+	w = do("PATCH", "/notes/1", map[string]interface{}{"body": "again"}, map[string]string{"If-Match": tag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestFieldModifiers(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name:    "Webhook",
+				History: true,
+				Fields: []config.FieldConfig{
+					{Name: "url", Type: "string", Required: true},
+					{Name: "deliveries", Type: "int", ReadOnly: true},
+					{Name: "signing_key", Type: "string", WriteOnly: true},
+					{Name: "internal_note", Type: "string", Hidden: true},
+				},
+			},
+		},
+	}
+
+	os.Remove("test_modifiers.db")
+	database, err := db.InitDB(cfg, "test_modifiers.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_modifiers.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBuffer(b)))
+		return w
+	}
+
+	// Read-only and hidden fields are ignored on writes, write-only fields are stored
+	w := do("POST", "/webhooks", map[string]interface{}{
+		"url": "https://example.com/hook", "deliveries": 50, "signing_key": "whsec_1", "internal_note": "x", "created_at": "2000-01-01T00:00:00Z",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var stored map[string]interface{}
+	database.Table("webhooks").Take(&stored)
+	assert.Nil(t, stored["deliveries"])
+	assert.Nil(t, stored["internal_note"])
+	assert.Equal(t, "whsec_1", stored["signing_key"])
+	assert.NotEqual(t, 2000, stored["created_at"].(time.Time).Year())
+
+	// Trusted writers may set read-only and hidden fields
+	_, err = srv.Seed([]config.SeedConfig{{Entity: "Webhook", Key: []string{"url"}, Records: []map[string]interface{}{
+		{"url": "https://example.com/hook", "deliveries": 3, "internal_note": "seeded"},
+	}}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/webhooks/1", map[string]interface{}{"deliveries": 0}).Code)
+
+	// Write-only and hidden fields never leave the database
+	for _, path := range []string{"/webhooks", "/webhooks/1", "/webhooks/1/history", "/webhooks?format=csv"} {
+		w = do("GET", path, nil)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "whsec_1", path)
+		assert.NotContains(t, w.Body.String(), "seeded", path)
+	}
+	assert.Contains(t, do("GET", "/webhooks/1", nil).Body.String(), `"deliveries":3`)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/webhooks?signing_key=nope", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/webhooks?sort=signing_key", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/webhooks/_facets?fields=internal_note", nil).Code)
+
+	// Contradicting modifiers stop the server
+	for _, field := range []config.FieldConfig{
+		{Name: "a", Type: "string", ReadOnly: true, WriteOnly: true},
+		{Name: "a", Type: "string", Hidden: true, WriteOnly: true},
+		{Name: "a", Type: "string", ReadOnly: true, Required: true},
+	} {
+		_, err := NewServer(&config.Config{Entities: []config.EntityConfig{{Name: "X", Fields: []config.FieldConfig{field}}}}, nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestEntityRulesOnPut(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{
+			{
+				Name: "Promotion",
+				Fields: []config.FieldConfig{
+					{Name: "price", Type: "float", Required: true},
+					{Name: "discount", Type: "float"},
+					{Name: "start_date", Type: "string", Format: "date"},
+					{Name: "end_date", Type: "string", Format: "date"},
+				},
+				Rules: []config.RuleConfig{
+					{Expr: "discount <= price", Message: "discount cannot exceed price"},
+					{Expr: "end_date == null or end_date >= start_date", Message: "end_date must not be before start_date"},
+				},
+			},
+		},
+	}
+
+	os.Remove("test_rules_put.db")
+	database, err := db.InitDB(cfg, "test_rules_put.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_rules_put.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBuffer(b)))
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/promotions", map[string]interface{}{"price": 100, "discount": 10, "start_date": "2024-01-01"}).Code)
+
+	// Rules see the payload merged over the stored record
+	w := do("PUT", "/promotions/1", map[string]interface{}{"price": 5})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "discount cannot exceed price")
+	assert.Equal(t, http.StatusOK, do("PUT", "/promotions/1", map[string]interface{}{"price": 50}).Code)
+
+	// Comparing with a missing optional field fails the rule with its message
+	w = do("PUT", "/promotions/1", map[string]interface{}{"price": 50, "start_date": nil, "end_date": "2024-01-31"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "end_date must not be before start_date")
+	assert.NotContains(t, w.Body.String(), "cannot compare")
+}
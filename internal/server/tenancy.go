@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"gorm.io/gorm"
+)
+
+// tenantID keeps tenant ids safe to use as file names.
+var tenantID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+type tenantKey struct{}
+
+type tenantDBKey struct{}
+
+// errUnknownTenant is returned for tenants without a database in database
+// mode.
+var errUnknownTenant = errors.New("unknown tenant")
+
+// tenantDatabases opens and caches the SQLite file of each tenant in
+// database mode.
+type tenantDatabases struct {
+	mu  sync.Mutex
+	dir string
+	dbs map[string]*gorm.DB
+}
+
+// open returns the database of a tenant. Only create makes a new file, so
+// requests can't create tenants, and with them files, by naming them.
+func (t *tenantDatabases) open(cfg *config.Config, tenant string, create bool) (*gorm.DB, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if database, ok := t.dbs[tenant]; ok {
+		return database, nil
+	}
+	path := filepath.Join(t.dir, tenant+".db")
+	if !create {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, errUnknownTenant
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, err
+	}
+	database, err := db.InitDB(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	t.dbs[tenant] = database
+	return database, nil
+}
+
+// checkTenancy validates the tenancy section and prepares database mode.
+func (s *Server) checkTenancy() error {
+	t := s.Config.Tenancy
+	if t == nil {
+		return nil
+	}
+	switch t.Mode {
+	case "", "shared":
+		column := s.Config.TenantColumn()
+		for _, entity := range s.Config.Entities {
+			for _, field := range entity.Fields {
+				if field.Name == column {
+					return fmt.Errorf("tenancy: entity %s: field %s is the tenant column", entity.Name, column)
+				}
+			}
+		}
+	case "database":
+		dir := t.Directory
+		if dir == "" {
+			dir = "tenants"
+		}
+		s.tenants = &tenantDatabases{dir: dir, dbs: map[string]*gorm.DB{}}
+	default:
+		return fmt.Errorf("tenancy: unknown mode %q, expected shared or database", t.Mode)
+	}
+	return nil
+}
+
+func tenantFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+func (s *Server) tenantClaim() string {
+	if s.Config.Tenancy != nil && s.Config.Tenancy.Claim != "" {
+		return s.Config.Tenancy.Claim
+	}
+	return "tenant_id"
+}
+
+// resolveTenant stores the tenant of a request in its context. The tenant
+// an API key is bound to, or the tenant claim in the caller's token, wins
+// and must agree with the header or subdomain when those are sent too.
+func (s *Server) resolveTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := s.Config.Tenancy
+		if t == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requested := ""
+		if t.Header != "" {
+			requested = r.Header.Get(t.Header)
+		}
+		if requested == "" && t.Subdomain {
+			requested = subdomain(r.Host)
+		}
+
+		tenant := requested
+		if p := PrincipalFromContext(r.Context()); p != nil {
+			if p.Tenant != "" {
+				if requested != "" && requested != p.Tenant {
+					writeError(w, http.StatusForbidden, fmt.Sprintf("API key belongs to tenant %s", p.Tenant))
+					return
+				}
+				tenant = p.Tenant
+			} else if claimed, ok := p.Claims[s.tenantClaim()].(string); ok && claimed != "" {
+				if requested != "" && requested != claimed {
+					writeError(w, http.StatusForbidden, fmt.Sprintf("token belongs to tenant %s", claimed))
+					return
+				}
+				tenant = claimed
+			}
+		}
+		if tenant == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !tenantID.MatchString(tenant) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid tenant %q", tenant))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant)))
+	})
+}
+
+// requireTenant rejects requests without a tenant and, in database mode,
+// opens the tenant's database for them.
+func (s *Server) requireTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Config.Tenancy == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenant := tenantFrom(r.Context())
+		if tenant == "" {
+			writeError(w, http.StatusBadRequest, "tenant required"+s.tenantHint())
+			return
+		}
+		if s.tenants == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		database, err := s.tenants.open(s.Config, tenant, false)
+		if err == errUnknownTenant {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown tenant %q", tenant))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantDBKey{}, database)))
+	})
+}
+
+func (s *Server) tenantHint() string {
+	if h := s.Config.Tenancy.Header; h != "" {
+		return ", send the " + h + " header"
+	}
+	return ""
+}
+
+// subdomain returns the first label of hosts like acme.api.example.com.
+func subdomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return ""
+	}
+	return labels[0]
+}
+
+// database returns the database of the request's tenant in database mode,
+// otherwise the server's database.
+func (s *Server) database(r *http.Request) *gorm.DB {
+	if database, ok := r.Context().Value(tenantDBKey{}).(*gorm.DB); ok {
+		return database
+	}
+	return s.DB
+}
+
+// tenantScope returns the tenant whose rows a shared-table query may see.
+func (s *Server) tenantScope(ctx context.Context) (string, bool) {
+	column := s.Config.TenantColumn()
+	if column == "" {
+		return "", false
+	}
+	tenant := tenantFrom(ctx)
+	return tenant, tenant != ""
+}
+
+// Tenant returns a copy of the server whose Import, Seed and Fake work on
+// the data of one tenant. In database mode it creates the tenant's database,
+// which is the only way new tenants come into being.
+func (s *Server) Tenant(tenant string) (*Server, error) {
+	if s.Config.Tenancy == nil {
+		return nil, fmt.Errorf("no tenancy section in the config")
+	}
+	if !tenantID.MatchString(tenant) {
+		return nil, fmt.Errorf("invalid tenant %q", tenant)
+	}
+	t := *s
+	t.tenant = tenant
+	if s.tenants != nil {
+		database, err := s.tenants.open(s.Config, tenant, true)
+		if err != nil {
+			return nil, err
+		}
+		t.DB = database
+	}
+	return &t, nil
+}
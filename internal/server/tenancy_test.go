@@ -0,0 +1,270 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func tenancyEntities() []config.EntityConfig {
+	return []config.EntityConfig{
+		{
+			Name:      "Team",
+			Fields:    []config.FieldConfig{{Name: "name", Type: "string", Unique: true}},
+			Relations: []config.RelationConfig{{Type: "has_many", Entity: "Member", Field: "team_id"}},
+		},
+		{
+			Name: "Member",
+			Fields: []config.FieldConfig{
+				{Name: "name", Type: "string"},
+				{Name: "team_id", Type: "int"},
+			},
+			Relations: []config.RelationConfig{{Type: "belongs_to", Entity: "Team", Field: "team_id"}},
+		},
+	}
+}
+
+func tenantRequester(srv *Server) func(method, path, tenant string, body interface{}) *httptest.ResponseRecorder {
+	return func(method, path, tenant string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(b))
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func TestSharedTenancy(t *testing.T) {
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: tenancyEntities(),
+		Tenancy:  &config.TenancyConfig{Header: "X-Tenant"},
+	}
+
+	os.Remove("test_tenancy.db")
+	database, err := db.InitDB(cfg, "test_tenancy.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_tenancy.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+	do := tenantRequester(srv)
+
+	w := do("GET", "/teams", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "X-Tenant")
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/teams", "../etc", nil).Code)
+
+	// Creates are stamped with the tenant, and unique values are per tenant
+	w = do("POST", "/teams", "acme", map[string]interface{}{"name": "Shared Name"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"tenant_id":"acme"`)
+	assert.Equal(t, http.StatusCreated, do("POST", "/teams", "globex", map[string]interface{}{"name": "Shared Name"}).Code)
+	assert.NotEqual(t, http.StatusCreated, do("POST", "/teams", "acme", map[string]interface{}{"name": "Shared Name"}).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/teams", "acme", map[string]interface{}{"name": "Other", "tenant_id": "globex"}).Code)
+
+	// Records of other tenants can't be read, changed or referenced
+	assert.Contains(t, do("GET", "/teams", "acme", nil).Body.String(), `"total":1`)
+	assert.Equal(t, http.StatusOK, do("GET", "/teams/1", "acme", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/teams/1", "globex", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("PATCH", "/teams/1", "globex", map[string]interface{}{"name": "Hijacked"}).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/teams/1", "globex", nil).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/teams/1", "acme", nil).Code)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/members", "globex", map[string]interface{}{"name": "Mallory", "team_id": 1}).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/members", "acme", map[string]interface{}{"name": "Alice", "team_id": 1}).Code)
+	w = do("GET", "/teams/2?expand=members", "globex", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Alice")
+
+	// The command line works on one tenant at a time
+	acme, err := srv.Tenant("acme")
+	assert.NoError(t, err)
+	_, err = acme.Seed([]config.SeedConfig{{Entity: "Team", Records: []map[string]interface{}{{"name": "Seeded"}}}})
+	assert.NoError(t, err)
+	assert.Contains(t, do("GET", "/teams", "acme", nil).Body.String(), `"total":2`)
+	assert.Contains(t, do("GET", "/teams", "globex", nil).Body.String(), `"total":1`)
+}
+
+func TestTenantClaim(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: []config.EntityConfig{{
+			Name: "User",
+			Fields: []config.FieldConfig{
+				{Name: "email", Type: "string", Unique: true},
+				{Name: "password", Type: "password"},
+			},
+		}},
+		Auth: &config.AuthConfig{
+			Users: &config.UsersConfig{Entity: "User", Secret: "test-secret"},
+		},
+		Tenancy: &config.TenancyConfig{Header: "X-Tenant"},
+	}
+
+	os.Remove("test_tenant_claim.db")
+	database, err := db.InitDB(cfg, "test_tenant_claim.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_tenant_claim.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+	acme, err := srv.Tenant("acme")
+	assert.NoError(t, err)
+	_, err = acme.Seed([]config.SeedConfig{{Entity: "User", Records: []map[string]interface{}{{"email": "ann@example.com", "password": "correct horse"}}}})
+	assert.NoError(t, err)
+
+	do := tenantRequester(srv)
+	login := map[string]interface{}{"email": "ann@example.com", "password": "correct horse"}
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/auth/login", "globex", login).Code)
+	w := do("POST", "/auth/login", "acme", login)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	// The token's claim picks the tenant and can't be overridden
+	get := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/users", nil)
+		req.Header.Set("Authorization", "Bearer "+resp.Data["access_token"].(string))
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Contains(t, get("").Body.String(), `"total":1`)
+	assert.Equal(t, http.StatusOK, get("acme").Code)
+	assert.Equal(t, http.StatusForbidden, get("globex").Code)
+
+	refresh := map[string]interface{}{"refresh_token": resp.Data["refresh_token"]}
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/auth/refresh", "globex", refresh).Code)
+	assert.Equal(t, http.StatusOK, do("POST", "/auth/refresh", "acme", refresh).Code)
+}
+
+func TestAPIKeyTenant(t *testing.T) {
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: tenancyEntities(),
+		Tenancy:  &config.TenancyConfig{Header: "X-Tenant"},
+		Auth: &config.AuthConfig{APIKeys: []config.APIKeyConfig{
+			{Name: "acme", Key: "acme-secret", Scopes: []string{"*:*"}, Tenant: "acme"},
+			{Name: "ops", Key: "ops-secret", Scopes: []string{"*:*"}},
+		}},
+	}
+
+	os.Remove("test_tenant_keys.db")
+	database, err := db.InitDB(cfg, "test_tenant_keys.db")
+	assert.NoError(t, err)
+	defer os.Remove("test_tenant_keys.db")
+
+	srv, err := NewServer(cfg, database)
+	assert.NoError(t, err)
+
+	do := func(method, key, tenant string) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(map[string]interface{}{"name": "Team"})
+		req := httptest.NewRequest(method, "/teams", bytes.NewBuffer(b))
+		req.Header.Set("X-API-Key", key)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		srv.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A bound key picks its tenant and can't switch to another one
+	w := do("POST", "acme-secret", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"tenant_id":"acme"`)
+	assert.Equal(t, http.StatusOK, do("GET", "acme-secret", "acme").Code)
+	w = do("GET", "acme-secret", "globex")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "API key belongs to tenant acme")
+
+	// Unbound keys reach every tenant
+	assert.Equal(t, http.StatusCreated, do("POST", "ops-secret", "globex").Code)
+	assert.Contains(t, do("GET", "ops-secret", "acme").Body.String(), `"total":1`)
+}
+
+func TestDatabaseTenancy(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Name: "Test API", Port: 8080},
+		Entities: tenancyEntities(),
+		Tenancy:  &config.TenancyConfig{Header: "X-Tenant", Mode: "database", Directory: dir},
+	}
+
+	srv, err := NewServer(cfg, nil)
+	assert.NoError(t, err)
+	do := tenantRequester(srv)
+
+	// Tenants are created from the command line, never by requests
+	w := do("POST", "/teams", "acme", map[string]interface{}{"name": "Acme"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown tenant")
+	_, err = os.Stat(filepath.Join(dir, "acme.db"))
+	assert.True(t, os.IsNotExist(err))
+	for _, tenant := range []string{"acme", "globex"} {
+		_, err := srv.Tenant(tenant)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/teams", "acme", map[string]interface{}{"name": "Acme"}).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/teams", "globex", map[string]interface{}{"name": "Acme"}).Code)
+	w = do("GET", "/teams/1", "globex", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "tenant_id")
+	assert.Contains(t, do("GET", "/teams", "acme", nil).Body.String(), `"total":1`)
+
+	for _, tenant := range []string{"acme", "globex"} {
+		_, err := os.Stat(filepath.Join(dir, tenant+".db"))
+		assert.NoError(t, err)
+	}
+
+	// Batches are authorized before the tenant is looked up
+	cfg.Auth = &config.AuthConfig{APIKeys: []config.APIKeyConfig{{Name: "app", Key: "app-secret", Scopes: []string{"*:*"}}}}
+	srv, err = NewServer(cfg, nil)
+	assert.NoError(t, err)
+	do = tenantRequester(srv)
+	batch := map[string]interface{}{"operations": []map[string]interface{}{{"method": "GET", "path": "/teams"}}}
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/_batch", "junk", batch).Code)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestInvalidTenancy(t *testing.T) {
+	_, err := NewServer(&config.Config{Entities: tenancyEntities(), Tenancy: &config.TenancyConfig{Mode: "schema"}}, nil)
+	assert.Error(t, err)
+
+	entities := tenancyEntities()
+	entities[0].Fields = append(entities[0].Fields, config.FieldConfig{Name: "tenant_id", Type: "string"})
+	_, err = NewServer(&config.Config{Entities: entities, Tenancy: &config.TenancyConfig{}}, nil)
+	assert.Error(t, err)
+
+	srv, err := NewServer(&config.Config{Entities: tenancyEntities()}, nil)
+	assert.NoError(t, err)
+	_, err = srv.Tenant("acme")
+	assert.Error(t, err)
+
+	keys := &config.AuthConfig{APIKeys: []config.APIKeyConfig{{Name: "a", Key: "k", Scopes: []string{"*:*"}, Tenant: "acme"}}}
+	_, err = NewServer(&config.Config{Entities: tenancyEntities(), Auth: keys}, nil)
+	assert.Error(t, err)
+	keys.APIKeys[0].Tenant = "../acme"
+	_, err = NewServer(&config.Config{Entities: tenancyEntities(), Auth: keys, Tenancy: &config.TenancyConfig{}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/jwt"
+)
+
+// tokenVerifier checks bearer tokens against the jwt section of the auth
+// config.
+type tokenVerifier struct {
+	keys jwt.Keys
+	cfg  config.JWTConfig
+}
+
+func newTokenVerifier(cfg *config.Config, jc *config.JWTConfig) (*tokenVerifier, error) {
+	v := &tokenVerifier{cfg: *jc}
+	if v.cfg.RolesClaim == "" {
+		v.cfg.RolesClaim = "roles"
+	}
+	if v.cfg.ScopesClaim == "" {
+		v.cfg.ScopesClaim = "scope"
+	}
+
+	secret := jc.Secret
+	if jc.SecretEnv != "" {
+		secret = os.Getenv(jc.SecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("%s is not set", jc.SecretEnv)
+		}
+	}
+	v.keys.Secret = []byte(secret)
+
+	if jc.JWKSFile != "" {
+		keys, err := jwt.LoadJWKS(jc.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys.Public = keys
+	}
+	if len(v.keys.Secret) == 0 && len(v.keys.Public) == 0 {
+		return nil, errors.New("set secret, secret_env or jwks_file")
+	}
+
+	for _, scope := range jc.DefaultScopes {
+		if err := checkScope(cfg, scope); err != nil {
+			return nil, fmt.Errorf("default_scopes: %w", err)
+		}
+	}
+	return v, nil
+}
+
+// verify checks a token and the configured claim requirements.
+func (v *tokenVerifier) verify(token string, now time.Time) (*Principal, error) {
+	claims, err := jwt.Verify(token, v.keys, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := claims.Time("exp"); v.cfg.RequireExp && !ok {
+		return nil, errors.New("missing exp")
+	}
+	if v.cfg.Issuer != "" && claims["iss"] != v.cfg.Issuer {
+		return nil, errors.New("unexpected issuer")
+	}
+	if v.cfg.Audience != "" && !contains(claims.Strings("aud"), v.cfg.Audience) {
+		return nil, errors.New("unexpected audience")
+	}
+
+	roles := claims.Strings(v.cfg.RolesClaim)
+	for _, role := range v.cfg.RequiredRoles {
+		if !contains(roles, role) {
+			return nil, fmt.Errorf("missing role %s", role)
+		}
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &Principal{
+		Subject: subject,
+		Scopes:  append(claims.Strings(v.cfg.ScopesClaim), v.cfg.DefaultScopes...),
+		Roles:   roles,
+		Claims:  claims,
+	}, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:]), true
+	}
+	return "", false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/iamajraj/skema/internal/expr"
+	"github.com/iamajraj/skema/internal/formats"
+	"gorm.io/gorm"
+)
+
+// ValidationError describes a single failing field and the rule it broke.
+type ValidationError struct {
+	Field   string                 `json:"field,omitempty"`
+	Rule    string                 `json:"rule"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// fieldRule is the precompiled form of a FieldConfig.
+type fieldRule struct {
+	config.FieldConfig
+	pattern *regexp.Regexp
+	min     *float64
+	max     *float64
+	format  *formats.Format
+}
+
+// validationPlan holds everything needed to validate an entity's payloads,
+// built once at startup so requests never compile regexes.
+type validationPlan struct {
+	fields    []fieldRule
+	relations []config.RelationConfig
+	rules     []entityRule
+}
+
+// entityRule is a compiled cross-field rule from EntityConfig.Rules.
+type entityRule struct {
+	config.RuleConfig
+	program *expr.Program
+}
+
+func buildValidationPlan(entity config.EntityConfig) (*validationPlan, error) {
+	plan := &validationPlan{}
+
+	switch entity.UnknownFields {
+	case "", "reject", "strip":
+	default:
+		return nil, fmt.Errorf("entity %s: unknown unknown_fields %q, expected reject or strip", entity.Name, entity.UnknownFields)
+	}
+
+	for _, field := range entity.Fields {
+		rule := fieldRule{FieldConfig: field}
+
+		if field.Pattern != "" {
+			re, err := regexp.Compile(field.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("entity %s: field %s: invalid pattern: %w", entity.Name, field.Name, err)
+			}
+			rule.pattern = re
+		}
+
+		if field.Min != nil {
+			bound := float64(*field.Min)
+			rule.min = &bound
+		}
+		if field.Max != nil {
+			bound := float64(*field.Max)
+			rule.max = &bound
+		}
+
+		switch {
+		case field.ReadOnly && field.WriteOnly:
+			return nil, fmt.Errorf("entity %s: field %s: read_only and write_only exclude each other, use hidden", entity.Name, field.Name)
+		case field.Hidden && field.WriteOnly:
+			return nil, fmt.Errorf("entity %s: field %s: hidden fields cannot be write_only", entity.Name, field.Name)
+		case field.Required && (field.ReadOnly || field.Hidden):
+			return nil, fmt.Errorf("entity %s: field %s: read_only and hidden fields cannot be required", entity.Name, field.Name)
+		}
+
+		if (field.MinLength != nil || field.MaxLength != nil) && field.Type != "string" && field.Type != "text" && field.Type != "password" {
+			return nil, fmt.Errorf("entity %s: field %s: min_length and max_length only apply to string, text and password fields", entity.Name, field.Name)
+		}
+
+		if field.Format != "" {
+			f, ok := formats.Lookup(field.Format)
+			if !ok {
+				return nil, fmt.Errorf("entity %s: field %s: unknown format %q", entity.Name, field.Name, field.Format)
+			}
+			rule.format = &f
+		}
+
+		plan.fields = append(plan.fields, rule)
+	}
+
+	for _, rel := range entity.Relations {
+		if rel.Type == "belongs_to" {
+			plan.relations = append(plan.relations, rel)
+		}
+	}
+
+	known := map[string]bool{"id": true, "created_at": true, "updated_at": true}
+	for _, field := range entity.Fields {
+		known[field.Name] = true
+	}
+	for i, rule := range entity.Rules {
+		program, err := expr.Compile(rule.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("entity %s: rule %d: %w", entity.Name, i+1, err)
+		}
+		for _, ident := range program.Identifiers() {
+			if !known[ident] {
+				return nil, fmt.Errorf("entity %s: rule %d: unknown field %q", entity.Name, i+1, ident)
+			}
+		}
+		plan.rules = append(plan.rules, entityRule{RuleConfig: rule, program: program})
+	}
+
+	return plan, nil
+}
+
+// validateData checks a full payload, as sent on create.
+func (s *Server) validateData(db *gorm.DB, entity config.EntityConfig, data map[string]interface{}) []ValidationError {
+	return s.validate(db, entity, data, nil, false)
+}
+
+// validateReplace checks a full payload sent on PUT. Fields are validated
+// like on create, while entity rules see data merged over the existing
+// record, since that is what gets stored.
+func (s *Server) validateReplace(db *gorm.DB, entity config.EntityConfig, data, existing map[string]interface{}) []ValidationError {
+	return s.validate(db, entity, data, existing, false)
+}
+
+// validatePatch checks a partial payload: only the fields present in data
+// are validated, and entity rules see data merged over the existing record.
+func (s *Server) validatePatch(db *gorm.DB, entity config.EntityConfig, data, existing map[string]interface{}) []ValidationError {
+	return s.validate(db, entity, data, existing, true)
+}
+
+func (s *Server) validate(db *gorm.DB, entity config.EntityConfig, data, existing map[string]interface{}, partial bool) []ValidationError {
+	plan := s.plans[entity.Name]
+
+	var errs []ValidationError
+
+	for _, field := range plan.fields {
+		val, exists := data[field.Name]
+		if partial && !exists {
+			continue
+		}
+
+		// Required check
+		if field.Required && (!exists || val == nil || val == "") {
+			errs = append(errs, ValidationError{
+				Field:   field.Name,
+				Rule:    "required",
+				Message: fmt.Sprintf("field '%s' is required", field.Name),
+			})
+			continue
+		}
+
+		if !exists || val == nil {
+			continue
+		}
+
+		// Type check
+		if !matchesType(field.Type, val) {
+			errs = append(errs, ValidationError{
+				Field:   field.Name,
+				Rule:    "type",
+				Message: fmt.Sprintf("field '%s' must be of type %s", field.Name, field.Type),
+				Params:  map[string]interface{}{"type": field.Type},
+			})
+			continue
+		}
+
+		// Min/Max for numbers
+		if field.Type == "int" || field.Type == "float" {
+			num, _ := toFloat(val)
+			if field.min != nil && num < *field.min {
+				errs = append(errs, ValidationError{
+					Field:   field.Name,
+					Rule:    "min",
+					Message: fmt.Sprintf("field '%s' must be at least %d", field.Name, *field.Min),
+					Params:  map[string]interface{}{"min": *field.Min},
+				})
+			}
+			if field.max != nil && num > *field.max {
+				errs = append(errs, ValidationError{
+					Field:   field.Name,
+					Rule:    "max",
+					Message: fmt.Sprintf("field '%s' must be at most %d", field.Name, *field.Max),
+					Params:  map[string]interface{}{"max": *field.Max},
+				})
+			}
+		}
+
+		str, isString := val.(string)
+		if !isString {
+			str = fmt.Sprintf("%v", val)
+		}
+
+		// Length checks for strings
+		if isString {
+			length := utf8.RuneCountInString(str)
+			if field.MinLength != nil && length < *field.MinLength {
+				errs = append(errs, ValidationError{
+					Field:   field.Name,
+					Rule:    "min_length",
+					Message: fmt.Sprintf("field '%s' must be at least %d characters", field.Name, *field.MinLength),
+					Params:  map[string]interface{}{"min_length": *field.MinLength},
+				})
+			}
+			if field.MaxLength != nil && length > *field.MaxLength {
+				errs = append(errs, ValidationError{
+					Field:   field.Name,
+					Rule:    "max_length",
+					Message: fmt.Sprintf("field '%s' must be at most %d characters", field.Name, *field.MaxLength),
+					Params:  map[string]interface{}{"max_length": *field.MaxLength},
+				})
+			}
+			if field.Type == "password" && len(str) > maxPasswordBytes && !isPasswordHash(str) {
+				errs = append(errs, ValidationError{
+					Field:   field.Name,
+					Rule:    "max_length",
+					Message: fmt.Sprintf("field '%s' must be at most %d bytes", field.Name, maxPasswordBytes),
+					Params:  map[string]interface{}{"max_length": maxPasswordBytes},
+				})
+			}
+		}
+
+		// Pattern check for strings
+		if field.pattern != nil && !field.pattern.MatchString(str) {
+			errs = append(errs, ValidationError{
+				Field:   field.Name,
+				Rule:    "pattern",
+				Message: fmt.Sprintf("field '%s' does not match pattern '%s'", field.Name, field.Pattern),
+				Params:  map[string]interface{}{"pattern": field.Pattern},
+			})
+		}
+
+		// Format checks
+		if field.format != nil && !field.format.Check(str) {
+			errs = append(errs, ValidationError{
+				Field:   field.Name,
+				Rule:    "format",
+				Message: fmt.Sprintf("field '%s' must be a valid %s", field.Name, field.Format),
+				Params:  map[string]interface{}{"format": field.Format},
+			})
+		}
+	}
+
+	// Relationship Validation
+	for _, rel := range plan.relations {
+		val, exists := data[rel.Field]
+		if exists && val != nil {
+			var count int64
+			s.relatedTable(db, rel.Entity).Where("id = ?", val).Count(&count)
+			if count == 0 {
+				errs = append(errs, ValidationError{
+					Field:   rel.Field,
+					Rule:    "relation",
+					Message: fmt.Sprintf("related %s with id %v does not exist", rel.Entity, val),
+					Params:  map[string]interface{}{"entity": rel.Entity, "id": val},
+				})
+			}
+		}
+	}
+
+	// Cross-field rules, skipping any that depend on a field that already failed
+	ruleEnv := data
+	if existing != nil {
+		ruleEnv = make(map[string]interface{}, len(existing)+len(data))
+		for k, v := range existing {
+			ruleEnv[k] = v
+		}
+		for k, v := range data {
+			ruleEnv[k] = v
+		}
+	}
+
+	failed := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		failed[e.Field] = true
+	}
+	for _, rule := range plan.rules {
+		skip := false
+		for _, ident := range rule.program.Identifiers() {
+			if failed[ident] {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+
+		ok, err := rule.program.EvalBool(ruleEnv)
+		if err == nil && ok {
+			continue
+		}
+
+		message := rule.Message
+		if message == "" {
+			message = fmt.Sprintf("rule '%s' failed", rule.Expr)
+		}
+		if err != nil {
+			message = fmt.Sprintf("%s (%v)", message, err)
+		}
+		errs = append(errs, ValidationError{
+			Rule:    "expression",
+			Message: message,
+			Params:  map[string]interface{}{"expr": rule.Expr, "fields": rule.program.Identifiers()},
+		})
+	}
+
+	return errs
+}
+
+// matchesType reports whether a decoded JSON value fits the declared field type.
+func matchesType(fieldType string, val interface{}) bool {
+	switch fieldType {
+	case "int":
+		num, ok := toFloat(val)
+		return ok && num == math.Trunc(num)
+	case "float":
+		_, ok := toFloat(val)
+		return ok
+	case "bool":
+		_, ok := val.(bool)
+		return ok
+	case "string", "text", "password":
+		_, ok := val.(string)
+		return ok
+	default:
+		return true
+	}
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/iamajraj/skema/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var benchEntity = config.EntityConfig{
+	Name: "User",
+	Fields: []config.FieldConfig{
+		{Name: "name", Type: "string", Required: true, Pattern: `^[a-zA-Z ]+$`},
+		{Name: "email", Type: "string", Format: "email"},
+		{Name: "age", Type: "int", Min: intPtr(18), Max: intPtr(120)},
+	},
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestInvalidPatternFailsAtStartup(t *testing.T) {
+	cfg := &config.Config{
+		Entities: []config.EntityConfig{
+			{Name: "User", Fields: []config.FieldConfig{{Name: "name", Type: "string", Pattern: "^[a-z"}}},
+		},
+	}
+
+	_, err := NewServer(cfg, nil)
+	assert.ErrorContains(t, err, "invalid pattern")
+}
+
+func TestInvalidUnknownFieldsFailsAtStartup(t *testing.T) {
+	cfg := &config.Config{
+		Entities: []config.EntityConfig{
+			{Name: "User", UnknownFields: "strict", Fields: []config.FieldConfig{{Name: "name", Type: "string"}}},
+		},
+	}
+
+	_, err := NewServer(cfg, nil)
+	assert.ErrorContains(t, err, "unknown_fields")
+}
+
+func BenchmarkValidateData(b *testing.B) {
+	s := &Server{plans: make(map[string]*validationPlan)}
+	plan, err := buildValidationPlan(benchEntity)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s.plans[benchEntity.Name] = plan
+
+	data := map[string]interface{}{
+		"name":  "Alice Smith",
+		"email": "alice@example.com",
+		"age":   float64(30),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if errs := s.validateData(nil, benchEntity, data); len(errs) > 0 {
+			b.Fatal(errs)
+		}
+	}
+}
+
+func TestStringLengthRules(t *testing.T) {
+	entity := config.EntityConfig{
+		Name: "Profile",
+		Fields: []config.FieldConfig{
+			{Name: "username", Type: "string", MinLength: intPtr(3), MaxLength: intPtr(8)},
+		},
+	}
+	plan, err := buildValidationPlan(entity)
+	assert.NoError(t, err)
+	s := &Server{plans: map[string]*validationPlan{entity.Name: plan}}
+
+	assert.Empty(t, s.validateData(nil, entity, map[string]interface{}{"username": "zoë"}))
+
+	errs := s.validateData(nil, entity, map[string]interface{}{"username": "al"})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "min_length", errs[0].Rule)
+
+	errs = s.validateData(nil, entity, map[string]interface{}{"username": "averylongname"})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "max_length", errs[0].Rule)
+
+	_, err = buildValidationPlan(config.EntityConfig{
+		Name:   "Bad",
+		Fields: []config.FieldConfig{{Name: "count", Type: "int", MaxLength: intPtr(3)}},
+	})
+	assert.Error(t, err)
+}
+
+func TestEntityRules(t *testing.T) {
+	entity := config.EntityConfig{
+		Name: "Promotion",
+		Fields: []config.FieldConfig{
+			{Name: "price", Type: "float"},
+			{Name: "discount", Type: "float"},
+			{Name: "start_date", Type: "string", Format: "date"},
+			{Name: "end_date", Type: "string", Format: "date"},
+		},
+		Rules: []config.RuleConfig{
+			{Expr: "discount <= price", Message: "discount cannot exceed price"},
+			{Expr: "end_date >= start_date", Message: "end_date must not be before start_date"},
+		},
+	}
+	plan, err := buildValidationPlan(entity)
+	assert.NoError(t, err)
+	s := &Server{plans: map[string]*validationPlan{entity.Name: plan}}
+
+	assert.Empty(t, s.validateData(nil, entity, map[string]interface{}{
+		"price": 100.0, "discount": 10.0, "start_date": "2024-01-01", "end_date": "2024-01-31",
+	}))
+
+	errs := s.validateData(nil, entity, map[string]interface{}{
+		"price": 100.0, "discount": 150.0, "start_date": "2024-02-01", "end_date": "2024-01-31",
+	})
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "expression", errs[0].Rule)
+	assert.Equal(t, "discount cannot exceed price", errs[0].Message)
+
+	// Rules touching a field that already failed are skipped
+	errs = s.validateData(nil, entity, map[string]interface{}{
+		"price": "free", "discount": 10.0, "start_date": "2024-01-01", "end_date": "2024-01-31",
+	})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "type", errs[0].Rule)
+
+	_, err = buildValidationPlan(config.EntityConfig{
+		Name:   "Bad",
+		Fields: []config.FieldConfig{{Name: "price", Type: "float"}},
+		Rules:  []config.RuleConfig{{Expr: "cost > price"}},
+	})
+	assert.ErrorContains(t, err, "unknown field")
+}